@@ -259,7 +259,7 @@ func inplaceChange(input, oldString, newString string) string {
 
 func failJob(jobID, message string, err error) {
 	failType := "JobFailed"
-	log.WithError(err).Fatal(message)
+	log.WithError(err).Error(message)
 	cp.PutJobFailureResult(&codepipeline.PutJobFailureResultInput{
 		JobId: &jobID,
 		FailureDetails: &codepipeline.FailureDetails{
@@ -24,20 +24,21 @@ func main() {
 
 func handler(context context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 
-	// Setup the basic EKS cluster info
-	cfg := &eksauth.ClusterConfig{
-		ClusterName: os.Getenv("CLUSTER_NAME"),
-	}
+	// Setup the basic EKS cluster info from CLUSTER_NAME and friends
+	cfg := &eksauth.ClusterConfig{}
+	cfg.LoadDefaultsFromEnv()
 
 	clientset, err := eksauth.NewAuthClient(cfg)
 	if err != nil {
-		log.WithError(err).Fatal(err.Error())
+		log.WithError(err).Error("Unable to create EKS client")
+		return events.APIGatewayProxyResponse{StatusCode: 500}, err
 	}
 
 	// Call Kubernetes API here
 	pods, err := clientset.CoreV1().Pods("").List(metav1.ListOptions{})
 	if err != nil {
-		log.WithError(err).Fatal("Error listing pods")
+		log.WithError(err).Error("Error listing pods")
+		return events.APIGatewayProxyResponse{StatusCode: 500}, err
 	}
 
 	var results []string
@@ -49,8 +50,8 @@ func handler(context context.Context, req events.APIGatewayProxyRequest) (events
 
 	json, err := json.Marshal(results)
 	if err != nil {
-		log.WithError(err).Fatal("Unable to marshal results to json")
-
+		log.WithError(err).Error("Unable to marshal results to json")
+		return events.APIGatewayProxyResponse{StatusCode: 500}, err
 	}
 
 	return events.APIGatewayProxyResponse{Body: string(json), StatusCode: 200}, nil
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/pkg/errors"
+)
+
+type AddonInfo struct {
+	Name            string
+	Version         string
+	Status          string
+	LatestVersion   string
+	UpdateAvailable bool
+}
+
+// ListAddons returns the EKS addons installed on the cluster along with
+// whether a newer version is available for the cluster's Kubernetes version.
+// In addition to eks:DescribeCluster this requires eks:ListAddons,
+// eks:DescribeAddon and eks:DescribeAddonVersions.
+func ListAddons(config *ClusterConfig) ([]AddonInfo, error) {
+	if err := config.resolveClusterName(); err != nil {
+		return nil, err
+	}
+	if err := config.ensureSession(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := config.lookupCluster(ctx); err != nil {
+		return nil, errors.Wrapf(err, "looking up cluster %q", config.ClusterName)
+	}
+	// The version is only known from a lookup, not from an endpoint and CA
+	// given by the caller.
+	if config.KubernetesVersion == "" {
+		if err := config.loadConfig(ctx); err != nil {
+			return nil, errors.Wrapf(err, "looking up cluster %q", config.ClusterName)
+		}
+	}
+	kubernetesVersion := config.KubernetesVersion
+
+	svc := config.eksAPI()
+
+	var names []string
+	err := svc.ListAddonsPages(&eks.ListAddonsInput{
+		ClusterName: aws.String(config.ClusterName),
+	}, func(page *eks.ListAddonsOutput, lastPage bool) bool {
+		names = append(names, aws.StringValueSlice(page.Addons)...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing addons for cluster %q", config.ClusterName)
+	}
+
+	addons := make([]AddonInfo, 0, len(names))
+	for _, name := range names {
+		out, err := svc.DescribeAddon(&eks.DescribeAddonInput{
+			ClusterName: aws.String(config.ClusterName),
+			AddonName:   aws.String(name),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "describing addon %q", name)
+		}
+		if out.Addon == nil {
+			return nil, errors.Errorf("addon %q not returned by DescribeAddon", name)
+		}
+
+		info := AddonInfo{
+			Name:    name,
+			Version: aws.StringValue(out.Addon.AddonVersion),
+			Status:  aws.StringValue(out.Addon.Status),
+		}
+
+		latest, err := latestAddonVersion(svc, name, kubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		info.LatestVersion = latest
+		info.UpdateAvailable = compareAddonVersions(latest, info.Version) > 0
+
+		config.logger().WithField("cluster", config.clusterID()).Debugf("Addon %s at %s, latest %s", name, info.Version, latest)
+		addons = append(addons, info)
+	}
+	return addons, nil
+}
+
+func latestAddonVersion(svc eksiface.EKSAPI, name, kubernetesVersion string) (string, error) {
+	latest := ""
+	err := svc.DescribeAddonVersionsPages(&eks.DescribeAddonVersionsInput{
+		AddonName:         aws.String(name),
+		KubernetesVersion: aws.String(kubernetesVersion),
+	}, func(page *eks.DescribeAddonVersionsOutput, lastPage bool) bool {
+		for _, addon := range page.Addons {
+			for _, v := range addon.AddonVersions {
+				version := aws.StringValue(v.AddonVersion)
+				if compareAddonVersions(version, latest) > 0 {
+					latest = version
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "describing versions of addon %q", name)
+	}
+	return latest, nil
+}
+
+// compareAddonVersions compares versions of the form v1.11.4-eksbuild.1,
+// returning a positive number if a is newer than b.
+func compareAddonVersions(a, b string) int {
+	pa, pb := addonVersionParts(a), addonVersionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+func addonVersionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	fields := strings.FieldsFunc(v, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	parts := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, _ := strconv.Atoi(f)
+		parts = append(parts, n)
+	}
+	return parts
+}
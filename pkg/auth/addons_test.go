@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+// fakeAddonsEKS serves the addons of a cluster, with addon versions
+// available for Kubernetes 1.17 only.
+type fakeAddonsEKS struct {
+	*fakeEKS
+	installed map[string]string
+	available map[string][]string
+
+	kubernetesVersions []string
+}
+
+func (f *fakeAddonsEKS) ListAddonsPages(input *eks.ListAddonsInput, fn func(*eks.ListAddonsOutput, bool) bool) error {
+	names := make([]string, 0, len(f.installed))
+	for _, name := range []string{"coredns", "kube-proxy", "vpc-cni"} {
+		if _, ok := f.installed[name]; ok {
+			names = append(names, name)
+		}
+	}
+	fn(&eks.ListAddonsOutput{Addons: aws.StringSlice(names)}, true)
+	return nil
+}
+
+func (f *fakeAddonsEKS) DescribeAddon(input *eks.DescribeAddonInput) (*eks.DescribeAddonOutput, error) {
+	return &eks.DescribeAddonOutput{Addon: &eks.Addon{
+		AddonName:    input.AddonName,
+		AddonVersion: aws.String(f.installed[aws.StringValue(input.AddonName)]),
+		Status:       aws.String(eks.AddonStatusActive),
+	}}, nil
+}
+
+func (f *fakeAddonsEKS) DescribeAddonVersionsPages(input *eks.DescribeAddonVersionsInput, fn func(*eks.DescribeAddonVersionsOutput, bool) bool) error {
+	version := aws.StringValue(input.KubernetesVersion)
+	f.kubernetesVersions = append(f.kubernetesVersions, version)
+	if version != "1.17" {
+		fn(&eks.DescribeAddonVersionsOutput{}, true)
+		return nil
+	}
+	for _, v := range f.available[aws.StringValue(input.AddonName)] {
+		fn(&eks.DescribeAddonVersionsOutput{Addons: []*eks.AddonInfo{{
+			AddonName:     input.AddonName,
+			AddonVersions: []*eks.AddonVersionInfo{{AddonVersion: aws.String(v)}},
+		}}}, false)
+	}
+	return nil
+}
+
+func newFakeAddonsEKS() *fakeAddonsEKS {
+	return &fakeAddonsEKS{
+		fakeEKS: newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E=")),
+		installed: map[string]string{
+			"coredns":    "v1.6.6-eksbuild.1",
+			"kube-proxy": "v1.17.17-eksbuild.2",
+		},
+		available: map[string][]string{
+			"coredns":    {"v1.6.6-eksbuild.1", "v1.6.10-eksbuild.1", "v1.6.9-eksbuild.3"},
+			"kube-proxy": {"v1.17.17-eksbuild.2", "v1.17.9-eksbuild.1"},
+		},
+	}
+}
+
+func TestListAddons(t *testing.T) {
+	svc := newFakeAddonsEKS()
+	config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: svc}
+
+	addons, err := ListAddons(config)
+	if err != nil {
+		t.Fatalf("ListAddons() = %v", err)
+	}
+	want := []AddonInfo{
+		{Name: "coredns", Version: "v1.6.6-eksbuild.1", Status: "ACTIVE", LatestVersion: "v1.6.10-eksbuild.1", UpdateAvailable: true},
+		{Name: "kube-proxy", Version: "v1.17.17-eksbuild.2", Status: "ACTIVE", LatestVersion: "v1.17.17-eksbuild.2"},
+	}
+	if !reflect.DeepEqual(addons, want) {
+		t.Errorf("ListAddons() = %+v, want %+v", addons, want)
+	}
+}
+
+func TestListAddonsGivenEndpoint(t *testing.T) {
+	svc := newFakeAddonsEKS()
+	config := &ClusterConfig{
+		ClusterName:              testClusterName,
+		MasterEndpoint:           "https://test.eks.amazonaws.com",
+		CertificateAuthorityData: "Y2E=",
+		Session:                  newFakeAWS().session(t),
+		EKS:                      svc,
+	}
+
+	addons, err := ListAddons(config)
+	if err != nil {
+		t.Fatalf("ListAddons() = %v", err)
+	}
+	// Without the version looked up, no addon versions would be found.
+	if addons[0].LatestVersion != "v1.6.10-eksbuild.1" {
+		t.Errorf("LatestVersion = %q, want v1.6.10-eksbuild.1", addons[0].LatestVersion)
+	}
+	for _, v := range svc.kubernetesVersions {
+		if v != "1.17" {
+			t.Errorf("addon versions requested for Kubernetes %q, want 1.17", v)
+		}
+	}
+}
+
+func TestCompareAddonVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.6.10-eksbuild.1", "v1.6.9-eksbuild.3", 1},
+		{"v1.7.5-eksbuild.1", "v1.7.5-eksbuild.2", -1},
+		{"v1.17.9-eksbuild.1", "v1.17.9-eksbuild.1", 0},
+		{"v1.0.0", "", 1},
+	}
+	for _, tt := range tests {
+		got := compareAddonVersions(tt.a, tt.b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compareAddonVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,115 +1,268 @@
 package auth
 
 import (
-	"encoding/base64"
+	"context"
 	"fmt"
+	"net"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // NewAuthClient creates a new EKS authenticated clientset.
-func NewAuthClient(config *ClusterConfig) (*clientset.Clientset, error) {
+//
+// The only AWS calls made are eks:DescribeCluster and sts:GetCallerIdentity,
+// so the caller needs no IAM permission beyond eks:DescribeCluster on the
+// cluster. Keep it that way: helpers needing other APIs must not be called
+// from this path.
+func NewAuthClient(config *ClusterConfig) (clientset.Interface, error) {
+	return NewAuthClientWithContext(context.Background(), config)
+}
+
+// NewAuthClientWithContext creates a new EKS authenticated clientset. The
+// context bounds the AWS calls made to look up the cluster and identity.
+func NewAuthClientWithContext(ctx context.Context, config *ClusterConfig) (clientset.Interface, error) {
+	result, err := newAuthClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return result.Clientset, nil
+}
+
+// NewAuthClientWithTimeout is like NewAuthClient, but gives up after timeout.
+// The error then matches context.DeadlineExceeded with errors.Is.
+func NewAuthClientWithTimeout(config *ClusterConfig, timeout time.Duration) (clientset.Interface, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cs, err := NewAuthClientWithContext(ctx, config)
+	if err != nil && ctx.Err() != nil {
+		// AWS errors do not unwrap to the context error.
+		return nil, errors.Wrapf(ctx.Err(), "creating client within %s: %v", timeout, err)
+	}
+	return cs, err
+}
+
+// AuthResult is a clientset created by NewAuthClientWithResult along with
+// what was learned while creating it.
+type AuthResult struct {
+	Clientset         clientset.Interface
+	Endpoint          string
+	KubernetesVersion string
+	ContextName       string
+	TokenExpiry       time.Time
+}
+
+// NewAuthClientWithResult is like NewAuthClient, but also returns the cluster
+// endpoint and version, the context name and when the embedded token
+// expires.
+func NewAuthClientWithResult(config *ClusterConfig) (*AuthResult, error) {
+	return newAuthClient(context.Background(), config)
+}
+
+func newAuthClient(ctx context.Context, config *ClusterConfig) (*AuthResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Set")
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Start new AWS session if not specified
-	if config.Session == nil {
-		config.Session = newSession()
+	if err := config.ensureSession(); err != nil {
+		return nil, err
 	}
 
 	// Load the rest from AWS using SDK
-	err := config.loadConfig()
+	err := config.lookupCluster(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to load Kubernetes Client Config")
 	}
 
 	// Create the Kubernetes client
-	client, err := config.NewClientConfig()
+	client, err := config.NewClientConfigWithContext(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Config")
 	}
 
-	clientset, err := client.NewClientSetWithEmbeddedToken()
+	tok, err := client.getToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Set")
+	}
+	restConfig, err := client.withToken(tok).restConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Set")
+	}
+	clientset, err := client.newForConfig(restConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Set")
 	}
 
-	return clientset, nil
+	return &AuthResult{
+		Clientset:         clientset,
+		Endpoint:          config.server(),
+		KubernetesVersion: config.KubernetesVersion,
+		ContextName:       client.ContextName,
+		TokenExpiry:       tok.Expiration,
+	}, nil
 }
 
 // Retrieve EKS cluster endpoint and CA from AWS
-func (c *ClusterConfig) loadConfig() error {
-	if c.ClusterName == "" {
-		errors.New("ClusterName cannot be empty")
+func (c *ClusterConfig) loadConfig(ctx context.Context) error {
+	return c.trace(ctx, "DescribeCluster", c.describeCluster)
+}
+
+func (c *ClusterConfig) describeCluster(ctx context.Context) error {
+	if err := c.resolveClusterName(); err != nil {
+		return err
 	}
 
-	svc := eks.New(c.Session)
+	svc := c.eksAPI()
 	input := &eks.DescribeClusterInput{
 		Name: aws.String(c.ClusterName),
 	}
 
-	log.WithField("cluster", c.ClusterName).Info("Looking up EKS cluster")
+	c.logger().WithField("cluster", c.clusterID()).Infof("Looking up EKS cluster")
 
-	result, err := svc.DescribeCluster(input)
+	var result *eks.DescribeClusterOutput
+	start := time.Now()
+	err := c.retryAWS(ctx, "DescribeCluster", func() (err error) {
+		result, err = svc.DescribeClusterWithContext(ctx, input, noSDKRetries)
+		return err
+	})
+	c.metrics().ObserveDescribeCluster(time.Since(start), err)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
-			log.WithField("cluster", c.ClusterName).Error(aerr.Error())
+			c.logger().WithField("cluster", c.clusterID()).Errorf("%s", aerr)
+			if aerr.Code() == eks.ErrCodeResourceNotFoundException {
+				return errors.Wrap(withKind(ErrClusterNotFound, err), aerr.Error())
+			}
 			return errors.Wrap(err, aerr.Error())
 		} else {
 			// Print the error, cast err to awserr.Error to get the Code and
 			// Message from an error.
-			log.WithField("cluster", c.ClusterName).Error(err.Error())
+			c.logger().WithField("cluster", c.clusterID()).Errorf("%s", err)
 			return errors.Wrap(err, err.Error())
 		}
 	}
 
-	log.WithField("cluster", c.ClusterName).Info("Found cluster")
-	log.WithField("cluster", result.Cluster).Debug("Cluster details")
+	cluster := result.Cluster
+	if cluster == nil {
+		return errors.Errorf("cluster %s not returned by DescribeCluster", c.clusterID())
+	}
+
+	c.logger().WithField("cluster", c.clusterID()).Infof("Found cluster")
+	c.logger().WithField("cluster", cluster).Debugf("Cluster details")
+
+	if status := aws.StringValue(cluster.Status); status != eks.ClusterStatusActive && !c.AllowInactiveCluster {
+		return errors.Errorf("cluster %s has status %s, expected %s", c.clusterID(), status, eks.ClusterStatusActive)
+	}
+
+	// Both are missing while the cluster is being created.
+	if aws.StringValue(cluster.Endpoint) == "" {
+		return errors.Errorf("cluster %s endpoint not yet available", c.clusterID())
+	}
+	if cluster.CertificateAuthority == nil || aws.StringValue(cluster.CertificateAuthority.Data) == "" {
+		return errors.Errorf("cluster %s certificate authority not yet available", c.clusterID())
+	}
 
-	c.MasterEndpoint = *result.Cluster.Endpoint
-	c.CertificateAuthorityData = *result.Cluster.CertificateAuthority.Data
+	c.MasterEndpoint = *cluster.Endpoint
+	c.CertificateAuthorityData = *cluster.CertificateAuthority.Data
+	c.KubernetesVersion = aws.StringValue(cluster.Version)
+	if vpc := cluster.ResourcesVpcConfig; vpc != nil {
+		c.EndpointPublicAccess = aws.BoolValue(vpc.EndpointPublicAccess)
+		c.EndpointPrivateAccess = aws.BoolValue(vpc.EndpointPrivateAccess)
+	}
+	if c.PreferPrivateEndpoint && !c.EndpointPrivateAccess {
+		c.logger().WithField("cluster", c.clusterID()).Warnf("Private endpoint access is disabled, using the public endpoint")
+	}
+	c.lookedUp = true
+	c.cacheCluster(ctx)
 	return nil
 }
 
 func (c *ClusterConfig) NewClientConfig() (*ClientConfig, error) {
+	return c.NewClientConfigWithContext(context.Background())
+}
 
-	stsAPI := sts.New(c.Session)
+// NewClientConfigWithContext is like NewClientConfig, with the context
+// bounding the STS caller identity lookup.
+func (c *ClusterConfig) NewClientConfigWithContext(ctx context.Context) (*ClientConfig, error) {
 
-	iamRoleARN, err := checkAuth(stsAPI)
-	if err != nil {
+	stsAPI := c.newSTS()
+	if err := c.checkSTSEndpoint(stsAPI.Endpoint); err != nil {
 		return nil, err
 	}
-	contextName := fmt.Sprintf("%s@%s", getUsername(iamRoleARN), c.ClusterName)
 
-	data, err := base64.StdEncoding.DecodeString(c.CertificateAuthorityData)
+	// Without the caller identity check the role is only known if this
+	// package assumes it.
+	iamRoleARN := c.assumedRole()
+	if !c.SkipCallerIdentity {
+		err := c.retryWebIdentity(func() (err error) {
+			iamRoleARN, err = c.checkAuth(ctx, stsAPI)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.newClientConfig(stsAPI, iamRoleARN)
+}
+
+// newClientConfig builds the kubeconfig for the caller role found by the
+// identity check, if any.
+func (c *ClusterConfig) newClientConfig(stsAPI stsiface.STSAPI, iamRoleARN string) (*ClientConfig, error) {
+	// The kubeconfig entry names may include the region so that clusters with
+	// the same name in different regions don't collide. The token is always
+	// generated for the real cluster name.
+	clusterEntry := c.ClusterName
+	if c.RegionalContextName {
+		clusterEntry = fmt.Sprintf("%s.%s", c.ClusterName, aws.StringValue(c.Session.Config.Region))
+	}
+	var contextName string
+	if c.ContextNameFunc != nil {
+		contextName = c.ContextNameFunc(iamRoleARN, clusterEntry)
+	} else {
+		contextName = fmt.Sprintf("%s@%s", c.username(iamRoleARN), clusterEntry)
+	}
+
+	data, err := c.caBundle()
 	if err != nil {
-		return nil, errors.Wrap(err, "decoding certificate authority data")
+		return nil, err
 	}
 
-	log.Info("Creating Kubernetes client config")
+	c.logger().Infof("Creating Kubernetes client config")
 	clientConfig := &ClientConfig{
 		Client: &clientcmdapi.Config{
 			Clusters: map[string]*clientcmdapi.Cluster{
-				c.ClusterName: {
-					Server:                   c.MasterEndpoint,
+				clusterEntry: {
+					Server:                   c.server(),
 					CertificateAuthorityData: data,
 				},
 			},
 			Contexts: map[string]*clientcmdapi.Context{
 				contextName: {
-					Cluster:  c.ClusterName,
+					Cluster:  clusterEntry,
 					AuthInfo: contextName,
 				},
 			},
@@ -122,43 +275,532 @@ func (c *ClusterConfig) NewClientConfig() (*ClientConfig, error) {
 		ContextName: contextName,
 		roleARN:     iamRoleARN,
 		sts:         stsAPI,
+		cluster:     c,
+		cached:      &tokenHolder{},
+		resources:   &resourcesHolder{},
 	}
 
 	return clientConfig, nil
+}
 
+// resolveClusterName sets ClusterName from ClusterARN if only the ARN is given.
+func (c *ClusterConfig) resolveClusterName() error {
+	if c.ClusterName != "" {
+		return nil
+	}
+	if c.ClusterARN == "" {
+		return errors.New("ClusterName cannot be empty")
+	}
+
+	name, err := parseClusterARN(c.ClusterARN)
+	if err != nil {
+		return err
+	}
+	c.ClusterName = name
+	return nil
+}
+
+// server returns the API server URL clients connect to.
+func (c *ClusterConfig) server() string {
+	if c.EndpointOverride != "" {
+		return c.EndpointOverride
+	}
+	return c.MasterEndpoint
+}
+
+// clusterID identifies the cluster in log output.
+func (c *ClusterConfig) clusterID() string {
+	if c == nil {
+		return ""
+	}
+	if c.ClusterARN != "" {
+		return c.ClusterARN
+	}
+	return c.ClusterName
 }
 
-func newSession() *session.Session {
+// ensureSession starts a new AWS session if none was specified, and otherwise
+// makes sure the given session is for the configured region.
+func (c *ClusterConfig) ensureSession() error {
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if c.Session == nil {
+		provider := SessionProvider
+		if provider == nil {
+			provider = func() (*session.Session, error) { return newSession(c) }
+		}
+		sess, err := provider()
+		if err != nil {
+			return errors.Wrap(err, "creating AWS session")
+		}
+		c.Session = sess
+		return c.checkFIPS()
+	}
+
+	sessionRegion := aws.StringValue(c.Session.Config.Region)
+	if c.Region != "" && c.Region != sessionRegion {
+		if sessionRegion != "" && !c.CopySessionToRegion {
+			return errors.Errorf("session region %q does not match cluster region %q", sessionRegion, c.Region)
+		}
+
+		c.logger().WithField("cluster", c.clusterID()).Debugf("Copying session from region %q to %q", sessionRegion, c.Region)
+		c.Session = c.Session.Copy(aws.NewConfig().WithRegion(c.Region))
+	}
+	return c.checkFIPS()
+}
+
+const defaultSessionDuration = 30 * time.Minute
+
+// defaultUsername names the context user when the caller role is unknown.
+const defaultUsername = "eksutil"
+
+// SessionProvider creates the session of configs without a Session, e.g. a
+// session with static credentials in tests. When nil, a session is created
+// from the environment and shared config, honoring Profile, AssumeRoleARN
+// and the other session options of the config.
+var SessionProvider func() (*session.Session, error)
+
+func newSession(c *ClusterConfig) (*session.Session, error) {
 	config := aws.NewConfig()
 	config = config.WithCredentialsChainVerboseErrors(true)
+	if c.Region != "" {
+		config = config.WithRegion(c.Region)
+	}
+
+	tokenProvider := c.mfaTokenProvider()
+
+	duration := c.SessionDuration
+	if duration == 0 {
+		duration = defaultSessionDuration
+	}
 
 	opts := session.Options{
 		Config:                  *config,
 		SharedConfigState:       session.SharedConfigEnable,
-		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+		AssumeRoleTokenProvider: tokenProvider,
+		AssumeRoleDuration:      duration,
+		Profile:                 c.Profile,
 	}
 
-	stscreds.DefaultDuration = 30 * time.Minute
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.UseWebIdentity {
+		creds, err := webIdentityCredentials(sess)
+		if err != nil {
+			return nil, err
+		}
+		sess = sess.Copy(aws.NewConfig().WithCredentials(creds))
+	}
+
+	// Each role is assumed with the credentials of the previous one.
+	roles := c.assumeRoleChain()
+	for i, roleARN := range roles {
+		last := i == len(roles)-1
+		c.logger().WithField("role", roleARN).Debugf("Assuming role for cluster access")
+		creds := stscreds.NewCredentials(sess, roleARN, func(p *stscreds.AssumeRoleProvider) {
+			if last && c.ExternalID != "" {
+				p.ExternalID = aws.String(c.ExternalID)
+			}
+			p.TokenProvider = tokenProvider
+			p.Duration = duration
+		})
+		sess = sess.Copy(aws.NewConfig().WithCredentials(creds))
+	}
+	return sess, nil
+}
+
+// assumeRoleChain returns AssumeRoleARNs followed by AssumeRoleARN.
+func (c *ClusterConfig) assumeRoleChain() []string {
+	roles := append([]string(nil), c.AssumeRoleARNs...)
+	if c.AssumeRoleARN != "" {
+		roles = append(roles, c.AssumeRoleARN)
+	}
+	return roles
+}
+
+// assumedRole returns the role the session ends up with if this package
+// assumes any, and otherwise an empty string.
+func (c *ClusterConfig) assumedRole() string {
+	roles := c.assumeRoleChain()
+	if len(roles) == 0 {
+		return ""
+	}
+	return roles[len(roles)-1]
+}
+
+// eksAPI returns the EKS client, built from the session unless EKS is set.
+func (c *ClusterConfig) eksAPI() eksiface.EKSAPI {
+	if c.EKS != nil {
+		return c.EKS
+	}
+	svc := eks.New(c.Session, c.eksConfig())
+	c.addUserAgent(&svc.Handlers)
+	return svc
+}
+
+// EKS requests go to the regional endpoint unless EKSEndpoint is set, e.g. to
+// reach the EKS API through a VPC endpoint.
+func (c *ClusterConfig) eksConfig() *aws.Config {
+	config := c.withFIPS(aws.NewConfig())
+	switch {
+	case c.EKSEndpoint != "":
+		config = config.WithEndpoint(c.EKSEndpoint)
+	case c.AWSEndpoint != "":
+		config = config.WithEndpoint(c.AWSEndpoint)
+	}
+	return config
+}
+
+// STS requests go to the EKS region unless STSRegion is set, in which case
+// that region is used for identity and token signing. The regional endpoint
+// is used unless STSRegionalEndpoint or AWS_STS_REGIONAL_ENDPOINTS asks for
+// the global one.
+func (c *ClusterConfig) stsConfig() *aws.Config {
+	config := c.withFIPS(aws.NewConfig())
+	if c.STSRegion != "" {
+		config = config.WithRegion(c.STSRegion)
+	}
+	if c.AWSEndpoint != "" {
+		config = config.WithEndpoint(c.AWSEndpoint)
+	}
+
+	switch {
+	case c.STSRegionalEndpoint != nil && !*c.STSRegionalEndpoint:
+		config = config.WithSTSRegionalEndpoint(endpoints.LegacySTSEndpoint)
+	case c.STSRegionalEndpoint != nil, c.STSRegion != "", !legacySTSFromEnv():
+		config = config.WithSTSRegionalEndpoint(endpoints.RegionalSTSEndpoint)
+	}
+	return config
+}
+
+// legacySTSFromEnv reports whether AWS_STS_REGIONAL_ENDPOINTS asks for the
+// global STS endpoint. Sessions default to it too, so their setting cannot be
+// told apart from an explicit one.
+func legacySTSFromEnv() bool {
+	return strings.EqualFold(os.Getenv("AWS_STS_REGIONAL_ENDPOINTS"), "legacy")
+}
 
-	return session.Must(session.NewSessionWithOptions(opts))
+func (c *ClusterConfig) checkAuth(ctx context.Context, stsAPI stsiface.STSAPI) (iamRoleARN string, err error) {
+	err = c.trace(ctx, "GetCallerIdentity", func(ctx context.Context) (err error) {
+		iamRoleARN, err = c.getCallerIdentity(ctx, stsAPI)
+		return err
+	})
+	return iamRoleARN, err
 }
 
-func checkAuth(stsAPI stsiface.STSAPI) (string, error) {
+func (c *ClusterConfig) getCallerIdentity(ctx context.Context, stsAPI stsiface.STSAPI) (string, error) {
 	input := &sts.GetCallerIdentityInput{}
-	output, err := stsAPI.GetCallerIdentity(input)
+	var output *sts.GetCallerIdentityOutput
+	start := time.Now()
+	err := c.retryAWS(ctx, "GetCallerIdentity", func() (err error) {
+		output, err = stsAPI.GetCallerIdentityWithContext(ctx, input, noSDKRetries)
+		return err
+	})
+	c.metrics().ObserveGetCallerIdentity(time.Since(start), err)
 	if err != nil {
+		if code := awsErrorCode(err); code == "AccessDenied" || code == "AccessDeniedException" {
+			err = withKind(ErrSTSAccessDenied, err)
+		}
 		return "", errors.Wrap(err, "checking AWS STS access – cannot get role ARN for current session")
 	}
 	iamRoleARN := *output.Arn
-	log.Debugf("role ARN for the current session is %s", iamRoleARN)
+	c.logger().Debugf("role ARN for the current session is %s", iamRoleARN)
 	return iamRoleARN, nil
 }
 
+// ClusterConfig describes how to find and authenticate to an EKS cluster.
+//
+// NewAuthClient and the other functions taking a ClusterConfig fill in the
+// session, endpoint and CA, so a ClusterConfig must not be used by several
+// goroutines at once. Give each goroutine its own copy instead; copies may
+// share a Session, which is safe for concurrent use. Call Close once the
+// config and the client configs created from it are no longer needed.
 type ClusterConfig struct {
-	ClusterName              string
+	ClusterName string
+
+	// ClusterARN identifies the cluster when ClusterName is empty. The name
+	// is taken from the ARN and the full ARN is used in log output.
+	ClusterARN string
+
+	// Region is the region of the cluster. The region from the environment
+	// or shared config is used when empty.
+	Region string
+
+	// Profile is the shared config profile used when the session is created
+	// by this package. Defaults to AWS_PROFILE or the default profile.
+	Profile string
+
+	// MasterEndpoint and CertificateAuthorityData are set by looking up the
+	// cluster. If both are given, NewAuthClient uses them without calling
+	// eks:DescribeCluster, e.g. where that permission is not granted; the
+	// fields set by the lookup, such as KubernetesVersion, then stay empty.
 	MasterEndpoint           string
 	CertificateAuthorityData string
-	Session                  *session.Session
+
+	// CAFile is a PEM file to read CertificateAuthorityData from when it is
+	// empty.
+	CAFile string
+
+	Session *session.Session
+
+	// KubernetesVersion is the control plane version, e.g. "1.17", set when
+	// the cluster is looked up.
+	KubernetesVersion string
+
+	// EndpointPublicAccess and EndpointPrivateAccess report whether the API
+	// server is reachable from the internet and from within the cluster VPC,
+	// set when the cluster is looked up.
+	EndpointPublicAccess  bool
+	EndpointPrivateAccess bool
+
+	// EndpointOverride replaces MasterEndpoint as the API server URL, e.g. an
+	// internal load balancer in front of the endpoint. It must be an https
+	// URL. Set TLSServerName to the MasterEndpoint hostname unless the
+	// server certificate is also valid for the override.
+	EndpointOverride string
+
+	// PreferPrivateEndpoint logs a warning when the cluster has private
+	// endpoint access disabled. EKS serves both endpoints under the same
+	// hostname, which resolves to the private endpoint from within the VPC
+	// when private access is enabled, so MasterEndpoint is used either way.
+	PreferPrivateEndpoint bool
+
+	// AutoRefreshCA looks up the cluster again and retries once when an API
+	// call fails because the server certificate is signed by an unknown
+	// authority, e.g. after the cluster CA has been rotated.
+	AutoRefreshCA bool
+
+	// MFAPromptFunc returns the MFA token code when an assumed role requires
+	// one, e.g. StdinMFAPrompt or a function reading a secret store in
+	// non-interactive setups. Defaults to prompting on stdin if it is a
+	// terminal, and failing with ErrNoMFATokenProvider otherwise.
+	MFAPromptFunc func() (string, error)
+
+	// STSRegion is the region of the STS endpoint used for the caller identity
+	// check and token generation. Defaults to the region of the session.
+	STSRegion string
+
+	// STSRegionalEndpoint selects between the regional STS endpoint and the
+	// global sts.amazonaws.com one. Defaults to regional, unless
+	// AWS_STS_REGIONAL_ENDPOINTS=legacy is set.
+	STSRegionalEndpoint *bool
+
+	// TokenCache stores generated tokens so they can be reused until they
+	// are about to expire. Tokens are not cached when nil.
+	TokenCache TokenCache
+
+	// AdditionalCABundle holds PEM certificates trusted in addition to the
+	// cluster CA, e.g. the CA of a TLS inspecting proxy in front of the API
+	// server.
+	AdditionalCABundle []byte
+
+	// AutoRefreshToken generates a new token and retries once when an API
+	// call is rejected with 401 Unauthorized, e.g. because the embedded token
+	// of a long lived clientset has expired.
+	AutoRefreshToken bool
+
+	// ProxyURL is the HTTP proxy used to reach the API server, e.g.
+	// http://proxy.example.com:3128. AWS API calls are not affected, their
+	// proxy is taken from the session or the environment.
+	ProxyURL string
+
+	// TLSServerName is sent as the SNI and used to verify the server
+	// certificate instead of the endpoint hostname, e.g. when the cluster is
+	// behind a gateway that routes on SNI.
+	TLSServerName string
+
+	// UseProtobuf requests protobuf encoding from the API server, falling back
+	// to JSON for resources that do not support it.
+	UseProtobuf bool
+
+	// LogContentTypeFallback logs a warning when UseProtobuf is set but the
+	// API server answers the first request with a content type other than
+	// protobuf.
+	LogContentTypeFallback bool
+
+	// EKS is the client used to look up the cluster, e.g. a fake in tests.
+	// Defaults to a client built from Session.
+	EKS eksiface.EKSAPI
+
+	// AWSEndpoint sends both EKS and STS requests to a single endpoint,
+	// e.g. http://localhost:4566 for LocalStack. It is meant for testing;
+	// EKSEndpoint takes precedence for EKS.
+	AWSEndpoint string
+
+	// UseFIPSEndpoints sends EKS and STS requests to FIPS endpoints. Creating
+	// a client fails if a region used has none.
+	UseFIPSEndpoints bool
+
+	// EKSEndpoint overrides the endpoint of the EKS API used to look up the
+	// cluster, e.g. a VPC interface endpoint with a custom hostname.
+	EKSEndpoint string
+
+	// RegionalContextName includes the region in the generated cluster and
+	// context names, e.g. user@prod.eu-west-1, so that kubeconfigs for
+	// clusters with the same name in several regions can be merged.
+	RegionalContextName bool
+
+	// ContextNameFunc names the generated context and user from the caller
+	// role ARN and the cluster entry name, which includes the region with
+	// RegionalContextName. Defaults to user@cluster.
+	ContextNameFunc func(roleARN, clusterName string) string
+
+	// SkipCallerIdentity skips the sts:GetCallerIdentity check, e.g. where
+	// it is denied by an SCP. The caller role is then unknown unless
+	// AssumeRoleARN is set.
+	SkipCallerIdentity bool
+
+	// Username replaces the name taken from the caller role in the default
+	// context name. Defaults to "eksutil" if the caller role is unknown.
+	Username string
+
+	// WebIdentityRetries is the number of times credential resolution is
+	// retried when the IRSA web identity token file is missing, which can
+	// happen briefly while it is rotated. Defaults to 3, negative disables.
+	WebIdentityRetries int
+
+	// WebIdentityRetryDelay is the delay between web identity retries.
+	// Defaults to 500ms.
+	WebIdentityRetryDelay time.Duration
+
+	// StrictMode turns warnings about insecure or deprecated setups into
+	// errors. See ErrGlobalSTSEndpoint, ErrEmbeddedToken and ErrInsecureTLS.
+	StrictMode bool
+
+	// RetryServerErrors retries idempotent API requests that fail with 429
+	// or 503, honoring the Retry-After header, e.g. while the EKS control
+	// plane is scaling.
+	RetryServerErrors bool
+
+	// ServerErrorRetries is the maximum number of retries per request when
+	// RetryServerErrors is set. Defaults to 3.
+	ServerErrorRetries int
+
+	// CopySessionToRegion copies a Session built for a different region into
+	// Region instead of failing on the mismatch. A Session without a region
+	// is always copied into Region.
+	CopySessionToRegion bool
+
+	// ClientSetFactory builds the clientset from the authenticated REST
+	// config, e.g. to return a fake clientset in tests. Defaults to
+	// kubernetes.NewForConfig.
+	ClientSetFactory func(*rest.Config) (clientset.Interface, error)
+
+	// Logger receives the log output of this package. Defaults to the
+	// standard logrus logger.
+	Logger Logger
+
+	// LogFormat is LogFormatText or LogFormatJSON, e.g. for CloudWatch Logs.
+	// When set and Logger is nil, logs go to a logger of this package with
+	// that format, at the level and to the output of the standard logrus
+	// logger, whose formatter is left alone.
+	LogFormat string
+
+	// UseWebIdentity takes the base credentials of the session from the IRSA
+	// web identity token, using AWS_ROLE_ARN, AWS_WEB_IDENTITY_TOKEN_FILE and
+	// optionally AWS_ROLE_SESSION_NAME, instead of the credential chain. The
+	// credentials of Profile are ignored; AssumeRoleARNs and AssumeRoleARN
+	// are still assumed on top. Only used when the session is created by
+	// this package.
+	UseWebIdentity bool
+
+	// AssumeRoleARN is a role assumed with the session credentials before
+	// calling AWS, e.g. a per-cluster access role in another account. The
+	// cluster lookup, the caller identity check and the token all use the
+	// assumed role. Only used when the session is created by this package;
+	// a given Session is used as is.
+	AssumeRoleARN string
+
+	// AssumeRoleARNs is a chain of roles assumed in order before
+	// AssumeRoleARN, each with the credentials of the previous one, e.g.
+	// automation, then landing zone, then cluster access. AWS limits chained
+	// role sessions to one hour regardless of SessionDuration.
+	AssumeRoleARNs []string
+
+	// ExternalID is passed when assuming the last role of the chain.
+	ExternalID string
+
+	// CacheTTL enables reuse of the cluster endpoint and CA looked up by
+	// NewAuthClient for the same cluster, by any config in the process, for
+	// the given duration. See DefaultCacheTTL. Disabled when zero. Configs
+	// share a lookup if they have the same cluster name, region, Profile,
+	// role chain, EKSEndpoint and AWSEndpoint. RefreshClusterCA always looks
+	// the cluster up again.
+	CacheTTL time.Duration
+
+	// AllowInactiveCluster skips the check that the cluster is ACTIVE, e.g.
+	// to reach a cluster that is UPDATING.
+	AllowInactiveCluster bool
+
+	// MaxRetries is the number of times the cluster lookup and the caller
+	// identity check are retried when AWS throttles or fails with a server
+	// error, instead of the retries of the session. Defaults to 3, negative
+	// disables.
+	MaxRetries int
+
+	// TokenCacheTTL is how long a generated token is reused before a new one
+	// is generated. It sets the expiration reported for the token, so it
+	// also applies to TokenCache and to tools reading the expiration.
+	//
+	// It does not make tokens any shorter lived: STS accepts a token for 15
+	// minutes after it was generated, whatever this is set to. It must not
+	// exceed 15 minutes. Defaults to 14 minutes.
+	TokenCacheTTL time.Duration
+
+	// SessionDuration is the duration of role sessions assumed by this
+	// package, either for AssumeRoleARN or a role_arn in the shared config.
+	// Defaults to 30 minutes.
+	SessionDuration time.Duration
+
+	// DialTimeout bounds connecting to the API server. When not positive,
+	// client-go's default of 30 seconds applies. Setting it costs the shared
+	// transport: client-go cannot cache transports with a custom dialer, so
+	// every client then gets its own connection pool.
+	DialTimeout time.Duration
+
+	// RequestTimeout bounds each API request, including reading the
+	// response. Watches and log streams are cut off after it as well, so
+	// leave it unset for clients that watch. Requests are not bounded by
+	// default; connecting is still bounded by DialTimeout and the TLS
+	// handshake by client-go.
+	RequestTimeout time.Duration
+
+	// QPS is the maximum sustained rate of API requests of the client.
+	// Defaults to 5, the client-go default.
+	QPS float32
+
+	// Burst is the maximum burst of API requests above QPS. Defaults to 10,
+	// the client-go default.
+	Burst int
+
+	// UserAgent identifies the client in API server audit logs and, appended
+	// to the AWS SDK user agent, in CloudTrail. Defaults to eksutil/<version>.
+	UserAgent string
+
+	// ImpersonateUser and ImpersonateGroups make API requests act as another
+	// user, which the authenticated role must be allowed to impersonate in
+	// RBAC.
+	ImpersonateUser   string
+	ImpersonateGroups []string
+
+	// Tracer starts spans around the cluster lookup, the caller identity
+	// check and token generation. No spans are recorded when nil.
+	Tracer Tracer
+
+	// Metrics receives the latency and outcome of cluster lookups, caller
+	// identity checks and token generation. Defaults to NoopMetrics.
+	Metrics Metrics
+
+	// Set once the endpoint and CA come from a cluster lookup rather than
+	// the caller.
+	lookedUp bool
+
+	// Set by Close.
+	closed int32
 }
 
 type ClientConfig struct {
@@ -167,6 +809,44 @@ type ClientConfig struct {
 	ContextName string
 	roleARN     string
 	sts         stsiface.STSAPI
+	cluster     *ClusterConfig
+
+	// TokenExpirySkew is how long before expiry a token is considered stale
+	// by WithCachedToken and the token cache. Defaults to 1 minute.
+	TokenExpirySkew time.Duration
+
+	// TokenGenerator generates the tokens, e.g. a fake in tests. Defaults
+	// to the aws-iam-authenticator generator.
+	TokenGenerator TokenGenerator
+
+	// Shared by copies of the config so they reuse the same token.
+	cached *tokenHolder
+
+	// Shared by copies of the config so they reuse discovered resources.
+	resources *resourcesHolder
+}
+
+// TokenGenerator generates a token for a cluster, signed with the given
+// STS client. It is implemented by the aws-iam-authenticator token.Generator.
+type TokenGenerator interface {
+	GetWithSTS(clusterID string, stsAPI stsiface.STSAPI) (token.Token, error)
+}
+
+type tokenHolder struct {
+	mu  sync.Mutex
+	tok token.Token
+}
+
+// username names the user of the generated context, after the last part of
+// the role ARN unless Username is set.
+func (c *ClusterConfig) username(iamRoleARN string) string {
+	switch {
+	case c.Username != "":
+		return c.Username
+	case iamRoleARN == "":
+		return defaultUsername
+	}
+	return getUsername(iamRoleARN)
 }
 
 func getUsername(iamRoleARN string) string {
@@ -177,49 +857,323 @@ func getUsername(iamRoleARN string) string {
 	return "iam-root-account"
 }
 
+func (c *ClientConfig) clusterID() string {
+	if c.cluster != nil {
+		return c.cluster.clusterID()
+	}
+	return c.ClusterName
+}
+
+// RoleARN returns the ARN of the IAM identity the client authenticates as,
+// as reported by STS. With SkipCallerIdentity it is the last assumed role,
+// or empty if no role is assumed by this package.
+func (c *ClientConfig) RoleARN() string {
+	return c.roleARN
+}
+
+// currentCluster returns the cluster entry of the current context.
+func (c *ClientConfig) currentCluster() *clientcmdapi.Cluster {
+	ctx, ok := c.Client.Contexts[c.ContextName]
+	if !ok {
+		return nil
+	}
+	return c.Client.Clusters[ctx.Cluster]
+}
+
+// CredentialProviderName returns the name of the provider in the AWS
+// credential chain that supplied the credentials, or an empty string if the
+// credentials cannot be resolved.
+func (c *ClientConfig) CredentialProviderName() string {
+	if c.cluster == nil || c.cluster.Session == nil || c.cluster.Session.Config.Credentials == nil {
+		return ""
+	}
+	creds, err := c.cluster.Session.Config.Credentials.Get()
+	if err != nil {
+		return ""
+	}
+	return creds.ProviderName
+}
+
+// Token returns a bearer token for the cluster and its expiration, the same
+// token WithEmbeddedToken embeds in the config.
+func (c *ClientConfig) Token() (string, time.Time, error) {
+	tok, err := c.getToken(context.Background())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tok.Token, tok.Expiration, nil
+}
+
+// WithEmbeddedToken returns a copy of the config whose current user has a
+// freshly generated token. The receiver is not modified.
 func (c *ClientConfig) WithEmbeddedToken() (*ClientConfig, error) {
+	tok, err := c.getToken(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return c.withToken(tok), nil
+}
+
+// WithCachedToken is like WithEmbeddedToken, but reuses the token generated
+// by a previous call until it is within TokenExpirySkew of expiring.
+func (c *ClientConfig) WithCachedToken() (*ClientConfig, error) {
+	if c.cached == nil {
+		c.cached = &tokenHolder{}
+	}
+
+	if err := c.cluster.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	c.cached.mu.Lock()
+	defer c.cached.mu.Unlock()
+
+	tok := c.cached.tok
+	if !tokenValid(tok, c.tokenExpirySkew()) {
+		var err error
+		if tok, err = c.getToken(context.Background()); err != nil {
+			return nil, err
+		}
+		c.cached.tok = tok
+	} else {
+		c.cluster.logger().WithField("cluster", c.clusterID()).Debugf("Reusing token")
+	}
+	return c.withToken(tok), nil
+}
+
+func (c *ClientConfig) withToken(tok token.Token) *ClientConfig {
+	clientConfigCopy := c.DeepCopy()
+	authInfo := clientConfigCopy.Client.AuthInfos[c.ContextName]
+	if authInfo == nil {
+		authInfo = clientcmdapi.NewAuthInfo()
+		if clientConfigCopy.Client.AuthInfos == nil {
+			clientConfigCopy.Client.AuthInfos = make(map[string]*clientcmdapi.AuthInfo)
+		}
+		clientConfigCopy.Client.AuthInfos[c.ContextName] = authInfo
+	}
+	authInfo.Token = tok.Token
+	return clientConfigCopy
+}
+
+// DeepCopy returns a copy of the config with its own kubeconfig, so changing
+// the clusters or users of either does not affect the other. The copy shares
+// the cluster config, token cache and discovered resources of the receiver.
+func (c *ClientConfig) DeepCopy() *ClientConfig {
+	if c == nil {
+		return nil
+	}
 	clientConfigCopy := *c
+	clientConfigCopy.Client = c.Client.DeepCopy()
+	return &clientConfigCopy
+}
+
+func (c *ClientConfig) tokenExpirySkew() time.Duration {
+	if c.TokenExpirySkew > 0 {
+		return c.TokenExpirySkew
+	}
+	return defaultTokenExpirySkew
+}
+
+// getToken returns a token from the configured token cache if it is still
+// valid, generating and caching a new one otherwise.
+func (c *ClientConfig) getToken(ctx context.Context) (tok token.Token, err error) {
+	err = c.cluster.trace(ctx, "GetToken", func(context.Context) (err error) {
+		tok, err = c.cachedOrNewToken()
+		return err
+	})
+	return tok, err
+}
+
+func (c *ClientConfig) cachedOrNewToken() (token.Token, error) {
+	if err := c.cluster.checkOpen(); err != nil {
+		return token.Token{}, err
+	}
 
-	log.Info("Generating token")
+	var cache TokenCache
+	if c.cluster != nil {
+		cache = c.cluster.TokenCache
+	}
 
-	gen, err := token.NewGenerator(true, false)
+	if cache != nil {
+		if tok, ok := cache.Get(c.tokenCacheKey()); ok && tokenValid(tok, c.tokenExpirySkew()) {
+			c.cluster.logger().WithField("cluster", c.clusterID()).Debugf("Using cached token")
+			return tok, nil
+		}
+	}
+	return c.generateToken()
+}
+
+// generateToken generates a new token and stores it in the token cache.
+func (c *ClientConfig) generateToken() (token.Token, error) {
+	if err := c.cluster.checkOpen(); err != nil {
+		return token.Token{}, err
+	}
+
+	ttl, err := c.cluster.tokenCacheTTL()
 	if err != nil {
-		return nil, errors.Wrap(err, "could not get token generator")
+		return token.Token{}, err
+	}
+
+	c.cluster.logger().Infof("Generating token")
+
+	gen := c.TokenGenerator
+	if gen == nil {
+		if gen, err = token.NewGenerator(true, false); err != nil {
+			return token.Token{}, errors.Wrap(withKind(ErrTokenGeneration, err), "could not get token generator")
+		}
+	}
+
+	if c.sts == nil && c.TokenGenerator == nil {
+		return token.Token{}, errors.New("client config has no STS client, create it with NewClientConfig")
 	}
 
-	tok, err := gen.GetWithSTS(c.ClusterName, c.sts.(*sts.STS))
+	var tok token.Token
+	start := time.Now()
+	err = c.cluster.retryWebIdentity(func() (err error) {
+		tok, err = gen.GetWithSTS(c.ClusterName, c.sts)
+		return err
+	})
+	c.cluster.metrics().ObserveTokenGeneration(time.Since(start), err)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not get token")
+		return token.Token{}, errors.Wrap(withKind(ErrTokenGeneration, err), "could not get token")
+	}
+	if ttl > 0 {
+		tok.Expiration = time.Now().Add(ttl)
 	}
 
-	x := c.Client.AuthInfos[c.ContextName]
-	x.Token = tok.Token
+	c.cluster.logger().WithField("token", tok).Debugf("Successfully generated token")
 
-	log.WithField("token", tok).Debug("Successfully generated token")
-	return &clientConfigCopy, nil
+	if c.cluster != nil && c.cluster.TokenCache != nil {
+		if err := c.cluster.TokenCache.Set(c.tokenCacheKey(), tok); err != nil {
+			c.cluster.logger().WithField("cluster", c.clusterID()).Errorf("Unable to cache token: %v", err)
+		}
+	}
+	return tok, nil
 }
 
-func (c *ClientConfig) NewClientSetWithEmbeddedToken() (*clientset.Clientset, error) {
-	clientConfig, err := c.WithEmbeddedToken()
+func (c *ClientConfig) NewClientSetWithEmbeddedToken() (clientset.Interface, error) {
+	restConfig, err := c.NewRESTConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "creating Kubernetes client config with embedded token")
 	}
-	clientSet, err := clientConfig.NewClientSet()
+	clientSet, err := c.newForConfig(restConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating Kubernetes client")
 	}
 	return clientSet, nil
 }
 
-func (c *ClientConfig) NewClientSet() (*clientset.Clientset, error) {
-	clientConfig, err := clientcmd.NewDefaultClientConfig(*c.Client, &clientcmd.ConfigOverrides{}).ClientConfig()
+// NewRESTConfig returns a REST client config with an embedded token, which
+// can be used to build typed, dynamic or controller-runtime clients.
+func (c *ClientConfig) NewRESTConfig() (*rest.Config, error) {
+	clientConfig, err := c.WithEmbeddedToken()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create API client configuration from client config")
+		return nil, err
+	}
+	return clientConfig.restConfig()
+}
+
+func (c *ClientConfig) NewClientSet() (clientset.Interface, error) {
+	restConfig, err := c.restConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := clientset.NewForConfig(clientConfig)
+	client, err := c.newForConfig(restConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create API client")
 	}
 	return client, nil
 }
+
+func (c *ClientConfig) newForConfig(restConfig *rest.Config) (clientset.Interface, error) {
+	if c.cluster != nil && c.cluster.ClientSetFactory != nil {
+		return c.cluster.ClientSetFactory(restConfig)
+	}
+	return clientset.NewForConfig(restConfig)
+}
+
+func (c *ClientConfig) restConfig() (*rest.Config, error) {
+	restConfig, err := clientcmd.NewDefaultClientConfig(*c.Client, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create API client configuration from client config")
+	}
+
+	if err := c.configureREST(restConfig); err != nil {
+		return nil, err
+	}
+	if err := c.cluster.checkTLS(restConfig); err != nil {
+		return nil, err
+	}
+	return restConfig, nil
+}
+
+// configureREST applies the ClusterConfig options to the REST client config.
+func (c *ClientConfig) configureREST(config *rest.Config) error {
+	config.UserAgent = c.cluster.userAgent()
+	if c.cluster == nil {
+		return nil
+	}
+
+	if c.cluster.TLSServerName != "" {
+		config.TLSClientConfig.ServerName = c.cluster.TLSServerName
+	}
+
+	if d := c.cluster.DialTimeout; d > 0 {
+		config.Dial = (&net.Dialer{Timeout: d, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if c.cluster.RequestTimeout > 0 {
+		config.Timeout = c.cluster.RequestTimeout
+	}
+
+	if c.cluster.QPS > 0 {
+		config.QPS = c.cluster.QPS
+	}
+	if c.cluster.Burst > 0 {
+		config.Burst = c.cluster.Burst
+	}
+
+	if c.cluster.ImpersonateUser != "" {
+		if config.BearerToken == "" {
+			return errors.New("ImpersonateUser requires a token, use NewClientSetWithEmbeddedToken")
+		}
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: c.cluster.ImpersonateUser,
+			Groups:   c.cluster.ImpersonateGroups,
+		}
+	}
+
+	if c.cluster.UseProtobuf {
+		config.AcceptContentTypes = protobufAccept
+		config.ContentType = runtime.ContentTypeProtobuf
+	}
+
+	// The proxy wrapper modifies the transport built by client-go, so it must
+	// be the innermost wrapper.
+	if c.cluster.ProxyURL != "" {
+		wrapper, err := proxyWrapper(c.cluster.ProxyURL, c.cluster.logger())
+		if err != nil {
+			return err
+		}
+		config.Wrap(wrapper)
+	}
+
+	// The CA refresh wrapper rebuilds the transport underneath it, along with
+	// the wrappers applied so far, so it must come next.
+	if c.cluster.AutoRefreshCA {
+		config.Wrap(c.caRefreshWrapper(config))
+	}
+
+	if c.cluster.AutoRefreshToken {
+		config.Wrap(c.tokenRefreshWrapper())
+	}
+
+	if c.cluster.RetryServerErrors {
+		config.Wrap(serverErrorRetrier(c.cluster.ServerErrorRetries, c.cluster.logger()))
+	}
+
+	if c.cluster.UseProtobuf && c.cluster.LogContentTypeFallback {
+		config.Wrap(contentTypeLogger(c.cluster.logger().WithField("cluster", c.clusterID())))
+	}
+	return nil
+}
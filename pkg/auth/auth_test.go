@@ -0,0 +1,1028 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/chankh/eksutil/pkg/auth/authtest"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestCredentialProviderName(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.CredentialProviderName(); got != credentials.StaticProviderName {
+		t.Errorf("CredentialProviderName() = %q, want %q", got, credentials.StaticProviderName)
+	}
+
+	if got := (&ClientConfig{}).CredentialProviderName(); got != "" {
+		t.Errorf("CredentialProviderName() without a session = %q, want empty", got)
+	}
+}
+
+func TestSTSRegionalEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		regional *bool
+		env      string
+		want     string
+	}{
+		{name: "default", want: "https://sts.us-west-2.amazonaws.com"},
+		{name: "enabled", regional: aws.Bool(true), env: "legacy", want: "https://sts.us-west-2.amazonaws.com"},
+		{name: "disabled", regional: aws.Bool(false), want: "https://sts.amazonaws.com"},
+		{name: "legacy env", env: "legacy", want: "https://sts.amazonaws.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClusterConfig{Session: newFakeAWS().session(t), STSRegionalEndpoint: tt.regional}
+			setEnv(t, "AWS_STS_REGIONAL_ENDPOINTS", tt.env)
+
+			if got := config.newSTS().Endpoint; got != tt.want {
+				t.Errorf("STS endpoint = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthClientAWSCalls(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	want := []string{"DescribeCluster", "GetCallerIdentity"}
+	if got := fake.operations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AWS calls = %v, want %v", got, want)
+	}
+}
+
+func TestEnsureSessionRegion(t *testing.T) {
+	fake := newFakeAWS()
+	tests := []struct {
+		name       string
+		region     string
+		session    *session.Session
+		copy       bool
+		wantRegion string
+		wantErr    bool
+	}{
+		{name: "same region", region: testRegion, session: fake.session(t), wantRegion: testRegion},
+		{name: "mismatch", region: "eu-west-1", session: fake.session(t), wantErr: true},
+		{name: "mismatch copied", region: "eu-west-1", session: fake.session(t), copy: true, wantRegion: "eu-west-1"},
+		{name: "session without region", region: "eu-west-1", session: fake.session(t).Copy(&aws.Config{Region: aws.String("")}), wantRegion: "eu-west-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClusterConfig{Region: tt.region, Session: tt.session, CopySessionToRegion: tt.copy, Logger: newTestLogger()}
+
+			err := config.ensureSession()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ensureSession() = nil, want a region mismatch error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ensureSession() = %v", err)
+			}
+			if got := aws.StringValue(config.Session.Config.Region); got != tt.wantRegion {
+				t.Errorf("session region = %q, want %q", got, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestNewAuthClientEmptyClusterName(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.ClusterName = ""
+
+	cs, err := NewAuthClient(config)
+	if err == nil {
+		t.Fatal("NewAuthClient() = nil error for an empty ClusterName")
+	}
+	if cs != nil {
+		t.Errorf("NewAuthClient() returned a clientset along with %v", err)
+	}
+}
+
+func TestNewSessionRegion(t *testing.T) {
+	newFakeAWS().installDefault(t)
+	setEnv(t, "AWS_REGION", testRegion)
+
+	config := &ClusterConfig{ClusterName: testClusterName, Region: "eu-west-1", Logger: newTestLogger()}
+	if err := config.ensureSession(); err != nil {
+		t.Fatal(err)
+	}
+	if got := aws.StringValue(config.Session.Config.Region); got != "eu-west-1" {
+		t.Errorf("session region = %q, want eu-west-1", got)
+	}
+}
+
+// newTestSessionConfig returns a config whose session is created by this
+// package, with requests served by fake.
+func newTestSessionConfig(t *testing.T, fake *fakeAWS) *ClusterConfig {
+	t.Helper()
+	fake.installDefault(t)
+	api := newFakeAPIServer(t)
+	fake.addCluster(testClusterName, api.URL, api.caData())
+	return &ClusterConfig{ClusterName: testClusterName, Region: testRegion, Logger: newTestLogger()}
+}
+
+func TestAssumeRoleARN(t *testing.T) {
+	const roleARN = "arn:aws:iam::111122223333:role/EKSAdmin"
+	fake := newFakeAWS()
+	config := newTestSessionConfig(t, fake)
+	config.AssumeRoleARN = roleARN
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+
+	calls := fake.callsTo("AssumeRole")
+	if len(calls) != 1 || calls[0].Params.Get("RoleArn") != roleARN {
+		t.Fatalf("AssumeRole calls = %+v, want one for %s", calls, roleARN)
+	}
+	key := fake.accessKeyFor(roleARN)
+	if len(fake.callsTo("GetCallerIdentity")) == 0 {
+		t.Error("caller identity not checked")
+	}
+	for _, call := range fake.callsTo("GetCallerIdentity") {
+		if call.AccessKeyID != key {
+			t.Errorf("GetCallerIdentity signed by %s, want the assumed role key %s", call.AccessKeyID, key)
+		}
+	}
+}
+
+func TestWithCachedToken(t *testing.T) {
+	client, err := testConfig(t, nil, nil).NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &fakeTokenGenerator{}
+	client.TokenGenerator = gen
+
+	fresh, err := client.WithCachedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.generated() != 1 {
+		t.Fatalf("%d tokens generated for a fresh config, want 1", gen.generated())
+	}
+
+	cached, err := client.WithCachedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.generated() != 1 || embeddedToken(cached) != embeddedToken(fresh) {
+		t.Errorf("valid token not reused: %d generated", gen.generated())
+	}
+
+	// Tokens about to expire within the skew are replaced.
+	client.cached.tok.Expiration = time.Now().Add(30 * time.Second)
+	expired, err := client.WithCachedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.generated() != 2 || embeddedToken(expired) == embeddedToken(fresh) {
+		t.Errorf("expiring token not replaced: %d generated", gen.generated())
+	}
+}
+
+func embeddedToken(c *ClientConfig) string {
+	return c.Client.AuthInfos[c.ContextName].Token
+}
+
+func TestClientSetFactory(t *testing.T) {
+	fakeClient := authtest.NewFakeAuthClient(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+	config := testConfig(t, nil, nil)
+	config.ClientSetFactory = authtest.FakeClientSetFactory(fakeClient)
+
+	cs, err := NewAuthClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs != fakeClient {
+		t.Fatalf("NewAuthClient() = %T, want the injected clientset", cs)
+	}
+	if _, err := cs.CoreV1().Namespaces().Get("team-a", metav1.GetOptions{}); err != nil {
+		t.Errorf("namespace of the fake clientset not found: %v", err)
+	}
+}
+
+func TestClusterARN(t *testing.T) {
+	const clusterARN = "arn:aws:eks:us-west-2:111122223333:cluster/test"
+
+	t.Run("valid", func(t *testing.T) {
+		fake := newFakeAWS()
+		config := testConfig(t, fake, nil)
+		config.ClusterName = ""
+		config.ClusterARN = clusterARN
+
+		if _, err := NewAuthClient(config); err != nil {
+			t.Fatalf("NewAuthClient() = %v", err)
+		}
+		if config.ClusterName != testClusterName {
+			t.Errorf("ClusterName = %q, want %q", config.ClusterName, testClusterName)
+		}
+		if n := len(fake.callsTo("DescribeCluster")); n != 1 {
+			t.Errorf("DescribeCluster called %d times, want 1", n)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, bad := range []string{"test", "arn:aws:eks:us-west-2:111122223333:nodegroup/test", "arn:aws:iam::111122223333:cluster/test"} {
+			config := testConfig(t, nil, nil)
+			config.ClusterName = ""
+			config.ClusterARN = bad
+			if _, err := NewAuthClient(config); err == nil {
+				t.Errorf("NewAuthClient() with ClusterARN %q = nil error", bad)
+			}
+		}
+	})
+
+	t.Run("both set", func(t *testing.T) {
+		config := testConfig(t, nil, nil)
+		config.ClusterARN = "arn:aws:eks:us-west-2:111122223333:cluster/other"
+
+		if _, err := NewAuthClient(config); err != nil {
+			t.Fatalf("NewAuthClient() = %v", err)
+		}
+		if config.ClusterName != testClusterName {
+			t.Errorf("ClusterName = %q, want the explicit %q", config.ClusterName, testClusterName)
+		}
+	})
+}
+
+func TestSessionDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{0, "1800"},
+		{45 * time.Minute, "2700"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration.String(), func(t *testing.T) {
+			defaultDuration := stscreds.DefaultDuration
+			fake := newFakeAWS()
+			config := newTestSessionConfig(t, fake)
+			config.AssumeRoleARN = "arn:aws:iam::111122223333:role/EKSAdmin"
+			config.SessionDuration = tt.duration
+
+			if _, err := NewAuthClient(config); err != nil {
+				t.Fatal(err)
+			}
+			calls := fake.callsTo("AssumeRole")
+			if len(calls) != 1 {
+				t.Fatalf("AssumeRole called %d times, want 1", len(calls))
+			}
+			if got := calls[0].Params.Get("DurationSeconds"); got != tt.want {
+				t.Errorf("DurationSeconds = %s, want %s", got, tt.want)
+			}
+			if stscreds.DefaultDuration != defaultDuration {
+				t.Errorf("stscreds.DefaultDuration changed to %s", stscreds.DefaultDuration)
+			}
+		})
+	}
+}
+
+func TestInjectedEKS(t *testing.T) {
+	fake := newFakeAWS()
+	svc := newFakeEKS(testCluster(testClusterName, "https://ABCD.gr7.us-west-2.eks.amazonaws.com", "Y2EtZGF0YQ=="))
+	config := &ClusterConfig{ClusterName: testClusterName, Session: fake.session(t), EKS: svc, Logger: newTestLogger()}
+
+	if err := config.loadConfig(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if config.MasterEndpoint != "https://ABCD.gr7.us-west-2.eks.amazonaws.com" || config.CertificateAuthorityData != "Y2EtZGF0YQ==" {
+		t.Errorf("endpoint and CA = %q, %q", config.MasterEndpoint, config.CertificateAuthorityData)
+	}
+	if svc.calls() != 1 || len(fake.callsTo("")) != 0 {
+		t.Errorf("%d calls to the injected client and %d to AWS, want 1 and 0", svc.calls(), len(fake.callsTo("")))
+	}
+}
+
+func TestProfile(t *testing.T) {
+	fake := newFakeAWS()
+	config := newTestSessionConfig(t, fake)
+	config.Profile = "team"
+	credentialsFile := "[team]\naws_access_key_id = AKIDTEAM\naws_secret_access_key = secret\n"
+	if err := ioutil.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), []byte(credentialsFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range fake.callsTo("") {
+		if call.AccessKeyID != "AKIDTEAM" {
+			t.Errorf("%s signed by %s, want the team profile key", call.Operation, call.AccessKeyID)
+		}
+	}
+}
+
+func TestClusterStatus(t *testing.T) {
+	tests := []struct {
+		status        string
+		allowInactive bool
+		wantErr       bool
+	}{
+		{status: eks.ClusterStatusActive},
+		{status: eks.ClusterStatusCreating, wantErr: true},
+		{status: eks.ClusterStatusUpdating, allowInactive: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			cluster := testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E=")
+			cluster.Status = aws.String(tt.status)
+			config := &ClusterConfig{
+				ClusterName:          testClusterName,
+				Session:              newFakeAWS().session(t),
+				EKS:                  newFakeEKS(cluster),
+				AllowInactiveCluster: tt.allowInactive,
+				Logger:               newTestLogger(),
+			}
+
+			err := config.loadConfig(context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.status) {
+					t.Errorf("loadConfig() = %v, want an error naming the status", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("loadConfig() = %v", err)
+			}
+		})
+	}
+}
+
+// Run with -race to check that copies of a config can be used concurrently.
+func TestNewAuthClientConcurrent(t *testing.T) {
+	fake := newFakeAWS()
+	base := newTestSessionConfig(t, fake)
+	base.AssumeRoleARN = "arn:aws:iam::111122223333:role/EKSAdmin"
+	base.SessionDuration = 45 * time.Minute
+	base.TokenCache = NewMemoryTokenCache()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := *base
+			if _, err := NewAuthClient(&config); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	client, err := testConfig(t, nil, nil).NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, expiry, err := client.Token()
+	if err != nil {
+		t.Fatalf("Token() = %v", err)
+	}
+	if !strings.HasPrefix(tok, "k8s-aws-v1.") {
+		t.Errorf("Token() = %q, want a k8s-aws-v1 token", tok)
+	}
+	if !expiry.After(time.Now()) {
+		t.Errorf("token expiry %s is not in the future", expiry)
+	}
+}
+
+func TestTokenGenerator(t *testing.T) {
+	client, err := testConfig(t, nil, nil).NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	embedded, err := client.WithEmbeddedToken()
+	if err != nil {
+		t.Fatalf("WithEmbeddedToken() = %v", err)
+	}
+	if got, want := embeddedToken(embedded), "k8s-aws-v1."+testClusterName+"-1"; got != want {
+		t.Errorf("AuthInfo token = %q, want %q", got, want)
+	}
+}
+
+func TestKubernetesVersion(t *testing.T) {
+	cluster := testCluster(testClusterName, "https://ABCD.gr7.us-west-2.eks.amazonaws.com", "Y2EtZGF0YQ==")
+	cluster.Version = aws.String("1.18")
+	config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: newFakeEKS(cluster), Logger: newTestLogger()}
+
+	if err := config.loadConfig(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if config.KubernetesVersion != "1.18" {
+		t.Errorf("KubernetesVersion = %q, want 1.18", config.KubernetesVersion)
+	}
+}
+
+func TestQPSAndBurst(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.QPS = 50
+	config.Burst = 100
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.QPS != 50 || restConfig.Burst != 100 {
+		t.Errorf("QPS and Burst = %v, %d, want 50, 100", restConfig.QPS, restConfig.Burst)
+	}
+
+	config.QPS, config.Burst = 0, 0
+	if restConfig, err = client.NewRESTConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.QPS != 0 || restConfig.Burst != 0 {
+		t.Errorf("QPS and Burst = %v, %d, want the client-go defaults", restConfig.QPS, restConfig.Burst)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "eksutil/" + Version; restConfig.UserAgent != want {
+		t.Errorf("default UserAgent = %q, want %q", restConfig.UserAgent, want)
+	}
+
+	config.UserAgent = "deployer/1.2.3"
+	if restConfig, err = client.NewRESTConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.UserAgent != "deployer/1.2.3" {
+		t.Errorf("UserAgent = %q, want deployer/1.2.3", restConfig.UserAgent)
+	}
+}
+
+func TestSessionProvider(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	fake.addCluster(testClusterName, api.URL, api.caData())
+	calls := 0
+	SessionProvider = func() (*session.Session, error) {
+		calls++
+		return fake.session(t), nil
+	}
+	t.Cleanup(func() { SessionProvider = nil })
+
+	config := &ClusterConfig{ClusterName: testClusterName, Logger: newTestLogger()}
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("SessionProvider called %d times, want 1", calls)
+	}
+	if len(fake.callsTo("DescribeCluster")) != 1 {
+		t.Errorf("AWS calls %q not made with the provided session", fake.operations())
+	}
+}
+
+func TestContextNameFunc(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	if client.ContextName != "tester@test" {
+		t.Errorf("default ContextName = %q, want tester@test", client.ContextName)
+	}
+
+	config.ContextNameFunc = func(roleARN, clusterName string) string {
+		return roleARN + "/" + clusterName
+	}
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testCallerARN + "/" + testClusterName
+	if client.ContextName != want || client.Client.CurrentContext != want {
+		t.Errorf("ContextName = %q, want %q", client.ContextName, want)
+	}
+	if _, ok := client.Client.AuthInfos[want]; !ok {
+		t.Errorf("no user named after the custom context %q", want)
+	}
+}
+
+func TestRegionalContextName(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := testConfig(t, nil, api)
+	config.RegionalContextName = true
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	entry := testClusterName + "." + testRegion
+	if want := "tester@" + entry; client.ContextName != want || client.Client.CurrentContext != want {
+		t.Errorf("ContextName = %q, want %q", client.ContextName, want)
+	}
+	if cluster := client.Client.Clusters[entry]; cluster == nil || cluster.Server != api.URL {
+		t.Errorf("cluster entry %q = %+v, want one for %s", entry, cluster, api.URL)
+	}
+	if got := client.Client.Contexts[client.ContextName].Cluster; got != entry {
+		t.Errorf("context cluster = %q, want %q", got, entry)
+	}
+
+	embedded, err := client.WithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok := embeddedToken(embedded); tok != "k8s-aws-v1."+testClusterName+"-1" {
+		t.Errorf("token = %q, want one generated for the cluster name", tok)
+	}
+	cs, err := client.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Discovery().ServerVersion(); err != nil {
+		t.Errorf("ServerVersion() = %v", err)
+	}
+}
+
+func TestIncompleteClusterDescription(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*eks.Cluster)
+		want   string
+	}{
+		{name: "nil endpoint", modify: func(c *eks.Cluster) { c.Endpoint = nil }, want: "endpoint not yet available"},
+		{name: "nil certificate authority", modify: func(c *eks.Cluster) { c.CertificateAuthority = nil }, want: "certificate authority not yet available"},
+		{name: "nil certificate authority data", modify: func(c *eks.Cluster) { c.CertificateAuthority.Data = nil }, want: "certificate authority not yet available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := testCluster(testClusterName, "https://ABCD.gr7.us-west-2.eks.amazonaws.com", "Y2EtZGF0YQ==")
+			tt.modify(cluster)
+			config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: newFakeEKS(cluster), Logger: newTestLogger()}
+
+			err := config.loadConfig(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadConfig() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthClientWithResult(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := testConfig(t, nil, api)
+
+	result, err := NewAuthClientWithResult(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Clientset == nil {
+		t.Error("Clientset is nil")
+	}
+	if result.Endpoint != api.URL {
+		t.Errorf("Endpoint = %q, want %q", result.Endpoint, api.URL)
+	}
+	if result.KubernetesVersion != "1.17" {
+		t.Errorf("KubernetesVersion = %q, want 1.17", result.KubernetesVersion)
+	}
+	if result.ContextName != "tester@test" {
+		t.Errorf("ContextName = %q, want tester@test", result.ContextName)
+	}
+	if !result.TokenExpiry.After(time.Now()) {
+		t.Errorf("TokenExpiry %s is not in the future", result.TokenExpiry)
+	}
+}
+
+func TestEndpointAccess(t *testing.T) {
+	tests := []struct {
+		name            string
+		public, private bool
+		wantWarning     bool
+	}{
+		{name: "public only", public: true, wantWarning: true},
+		{name: "public and private", public: true, private: true},
+		{name: "private only", private: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := testCluster(testClusterName, "https://ABCD.gr7.us-west-2.eks.amazonaws.com", "Y2EtZGF0YQ==")
+			cluster.ResourcesVpcConfig.EndpointPublicAccess = aws.Bool(tt.public)
+			cluster.ResourcesVpcConfig.EndpointPrivateAccess = aws.Bool(tt.private)
+			logger := newTestLogger()
+			config := &ClusterConfig{
+				ClusterName:           testClusterName,
+				Session:               newFakeAWS().session(t),
+				EKS:                   newFakeEKS(cluster),
+				Logger:                logger,
+				PreferPrivateEndpoint: true,
+			}
+
+			if err := config.loadConfig(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if config.EndpointPublicAccess != tt.public || config.EndpointPrivateAccess != tt.private {
+				t.Errorf("public and private access = %t, %t, want %t, %t",
+					config.EndpointPublicAccess, config.EndpointPrivateAccess, tt.public, tt.private)
+			}
+			if config.MasterEndpoint != "https://ABCD.gr7.us-west-2.eks.amazonaws.com" {
+				t.Errorf("MasterEndpoint = %q, want the DescribeCluster endpoint", config.MasterEndpoint)
+			}
+			if _, warned := logger.find("warn", "Private endpoint access is disabled, using the public endpoint"); warned != tt.wantWarning {
+				t.Errorf("warned about private access: %t, want %t", warned, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.ImpersonateUser = "alice"
+	config.ImpersonateGroups = []string{"auditors"}
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Impersonate.UserName != "alice" || !reflect.DeepEqual(restConfig.Impersonate.Groups, []string{"auditors"}) {
+		t.Errorf("Impersonate = %+v, want alice in auditors", restConfig.Impersonate)
+	}
+
+	if _, err := client.NewClientSet(); err == nil {
+		t.Error("NewClientSet() = nil error when impersonating without a token")
+	}
+}
+
+func TestSkipCallerIdentity(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.SkipCallerIdentity = true
+
+	result, err := NewAuthClientWithResult(config)
+	if err != nil {
+		t.Fatalf("NewAuthClientWithResult() = %v", err)
+	}
+	if result.Clientset == nil {
+		t.Error("no clientset built")
+	}
+	if calls := fake.callsTo("GetCallerIdentity"); len(calls) != 0 {
+		t.Errorf("GetCallerIdentity called %d times", len(calls))
+	}
+	if result.ContextName != defaultUsername+"@"+testClusterName {
+		t.Errorf("ContextName = %q, want the placeholder user", result.ContextName)
+	}
+
+	config.Username = "deployer"
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.ContextName != "deployer@"+testClusterName {
+		t.Errorf("ContextName = %q, want deployer@%s", client.ContextName, testClusterName)
+	}
+}
+
+func TestNewAuthClientWithTimeout(t *testing.T) {
+	fake := newFakeAWS()
+	fake.delay = 5 * time.Second
+	config := testConfig(t, fake, nil)
+
+	start := time.Now()
+	_, err := NewAuthClientWithTimeout(config, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("NewAuthClientWithTimeout() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %s, want about 50ms", elapsed)
+	}
+}
+
+func TestAssumeRoleChain(t *testing.T) {
+	roles := []string{
+		"arn:aws:iam::111122223333:role/automation",
+		"arn:aws:iam::444455556666:role/landing-zone",
+		"arn:aws:iam::777788889999:role/cluster-access",
+	}
+	fake := newFakeAWS()
+	config := newTestSessionConfig(t, fake)
+	config.AssumeRoleARNs = roles[:2]
+	config.AssumeRoleARN = roles[2]
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	calls := fake.callsTo("AssumeRole")
+	if len(calls) != len(roles) {
+		t.Fatalf("AssumeRole called %d times, want %d", len(calls), len(roles))
+	}
+	signer := testAccessKeyID
+	for i, call := range calls {
+		if got := call.Params.Get("RoleArn"); got != roles[i] {
+			t.Errorf("hop %d assumed %s, want %s", i, got, roles[i])
+		}
+		if call.AccessKeyID != signer {
+			t.Errorf("hop %d signed by %s, want %s", i, call.AccessKeyID, signer)
+		}
+		signer = fake.accessKeyFor(roles[i])
+	}
+	for _, call := range fake.callsTo("DescribeCluster") {
+		if call.AccessKeyID != signer {
+			t.Errorf("DescribeCluster signed by %s, want the last role key %s", call.AccessKeyID, signer)
+		}
+	}
+}
+
+func TestRoleARN(t *testing.T) {
+	const roleARN = "arn:aws:iam::111122223333:role/EKSAdmin"
+	tests := []struct {
+		name               string
+		assumeRoleARN      string
+		skipCallerIdentity bool
+		want               string
+	}{
+		{name: "caller identity", want: testCallerARN},
+		{name: "assumed role", assumeRoleARN: roleARN, want: assumedRoleARN(roleARN)},
+		{name: "skipped caller identity", assumeRoleARN: roleARN, skipCallerIdentity: true, want: roleARN},
+		{name: "skipped without role", skipCallerIdentity: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestSessionConfig(t, newFakeAWS())
+			config.AssumeRoleARN = tt.assumeRoleARN
+			config.SkipCallerIdentity = tt.skipCallerIdentity
+			if err := config.ensureSession(); err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := config.NewClientConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := client.RoleARN(); got != tt.want {
+				t.Errorf("RoleARN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossAccountRole(t *testing.T) {
+	const roleARN = "arn:aws:iam::444455556666:role/ClusterAccess"
+	fake := newFakeAWS()
+	config := newTestSessionConfig(t, fake)
+	config.AssumeRoleARN = roleARN
+	if err := config.ensureSession(); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.lookupCluster(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, _, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := fake.accessKeyFor(roleARN)
+	for _, op := range []string{"DescribeCluster", "GetCallerIdentity"} {
+		calls := fake.callsTo(op)
+		if len(calls) != 1 || calls[0].AccessKeyID != key {
+			t.Errorf("%s calls = %+v, want one signed by the cross-account key %s", op, calls, key)
+		}
+	}
+	if got := tokenAccessKeyID(t, tok); got != key {
+		t.Errorf("token signed by %s, want the cross-account key %s", got, key)
+	}
+}
+
+// tokenAccessKeyID returns the access key ID of the presigned
+// GetCallerIdentity request encoded in a token.
+func tokenAccessKeyID(t *testing.T, tok string) string {
+	t.Helper()
+	presigned, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(tok, "k8s-aws-v1."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(string(presigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitN(u.Query().Get("X-Amz-Credential"), "/", 2)[0]
+}
+
+func TestAWSEndpoint(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.AWSEndpoint = "http://localstack:4566"
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	for _, op := range []string{"DescribeCluster", "GetCallerIdentity"} {
+		calls := fake.callsTo(op)
+		if len(calls) != 1 || calls[0].Host != "localstack:4566" {
+			t.Errorf("%s calls = %+v, want one to localstack:4566", op, calls)
+		}
+	}
+
+	resetClusterCache(t)
+	config.EKSEndpoint = "https://vpce-eks.example.com"
+	if err := config.loadConfig(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.callsTo("DescribeCluster")
+	if last := calls[len(calls)-1]; last.Host != "vpce-eks.example.com" {
+		t.Errorf("DescribeCluster sent to %s, want EKSEndpoint to take precedence", last.Host)
+	}
+}
+
+func TestEndpointOverride(t *testing.T) {
+	const override = "https://internal-lb.example.com:8443"
+	config := testConfig(t, nil, nil)
+	config.EndpointOverride = override
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	if got := client.Endpoint(); got != override {
+		t.Errorf("Server = %q, want %q", got, override)
+	}
+	tok, _, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != "k8s-aws-v1."+testClusterName+"-1" {
+		t.Errorf("token %q not generated for the cluster name", tok)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Timeout != 0 {
+		t.Errorf("default Timeout = %s, want none so that watches are not cut off", restConfig.Timeout)
+	}
+
+	config.RequestTimeout = 30 * time.Second
+	if restConfig, err = client.NewRESTConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %s, want 30s", restConfig.Timeout)
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.TLSClientConfig.ServerName != "" {
+		t.Errorf("default ServerName = %q, want the endpoint host", restConfig.TLSClientConfig.ServerName)
+	}
+
+	// The test server certificate is valid for example.com.
+	config.TLSServerName = "example.com"
+	if restConfig, err = client.NewRESTConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.TLSClientConfig.ServerName != "example.com" {
+		t.Errorf("ServerName = %q, want example.com", restConfig.TLSClientConfig.ServerName)
+	}
+	cs, err := client.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Discovery().ServerVersion(); err != nil {
+		t.Errorf("ServerVersion() with ServerName example.com = %v", err)
+	}
+
+	config.TLSServerName = "other.example.org"
+	if cs, err = client.NewClientSetWithEmbeddedToken(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.Discovery().ServerVersion(); err == nil {
+		t.Error("ServerVersion() = nil error for a ServerName not in the server certificate")
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	// Without a dialer client-go shares one transport between clients.
+	transports := make([]http.RoundTripper, 2)
+	for i := range transports {
+		restConfig, err := client.NewRESTConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restConfig.Dial != nil {
+			t.Fatal("dialer set without DialTimeout")
+		}
+		if transports[i], err = rest.TransportFor(restConfig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if innermostTransport(transports[0]) != innermostTransport(transports[1]) {
+		t.Error("clients without DialTimeout do not share the cached transport")
+	}
+
+	config.DialTimeout = 5 * time.Second
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Dial == nil {
+		t.Error("no dialer set for DialTimeout")
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+	client := lookedUpClientConfig(t, testConfig(t, nil, nil))
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	first, err := client.WithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.WithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := embeddedToken(first), "k8s-aws-v1."+testClusterName+"-1"; got != want {
+		t.Errorf("first token = %q, want %q after generating the second", got, want)
+	}
+	if got, want := embeddedToken(second), "k8s-aws-v1."+testClusterName+"-2"; got != want {
+		t.Errorf("second token = %q, want %q", got, want)
+	}
+
+	copied := client.DeepCopy()
+	copied.currentCluster().Server = "https://changed.example.com"
+	if client.Endpoint() == "https://changed.example.com" {
+		t.Error("changing the copy changed the original cluster")
+	}
+}
+
+func TestWithEmbeddedTokenLeavesReceiver(t *testing.T) {
+	client := lookedUpClientConfig(t, testConfig(t, nil, nil))
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	embedded, err := client.WithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embeddedToken(embedded) == "" {
+		t.Error("no token embedded in the returned config")
+	}
+	if tok := embeddedToken(client); tok != "" {
+		t.Errorf("receiver token = %q, want it left empty", tok)
+	}
+}
@@ -0,0 +1,39 @@
+// Package authtest provides helpers for testing code built on top of
+// auth.NewAuthClient without talking to AWS or a real cluster.
+//
+// Code under test should depend on kubernetes.Interface rather than the
+// concrete clientset, and obtain it through a replaceable function:
+//
+//	var newClient = func() (kubernetes.Interface, error) {
+//		return auth.NewAuthClient(&auth.ClusterConfig{ClusterName: "prod"})
+//	}
+//
+// Tests then swap in a fake:
+//
+//	newClient = func() (kubernetes.Interface, error) {
+//		return authtest.NewFakeAuthClient(pod), nil
+//	}
+//
+// To exercise the real authentication flow but stop short of the API server,
+// set ClusterConfig.ClientSetFactory to FakeClientSetFactory instead.
+package authtest
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// NewFakeAuthClient returns an in-memory clientset pre-populated with objects.
+func NewFakeAuthClient(objects ...runtime.Object) *fake.Clientset {
+	return fake.NewSimpleClientset(objects...)
+}
+
+// FakeClientSetFactory returns a ClusterConfig.ClientSetFactory that ignores
+// the REST config and returns the given fake clientset.
+func FakeClientSetFactory(cs *fake.Clientset) func(*rest.Config) (kubernetes.Interface, error) {
+	return func(*rest.Config) (kubernetes.Interface, error) {
+		return cs, nil
+	}
+}
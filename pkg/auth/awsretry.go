@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const (
+	defaultMaxRetries = 3
+	baseRetryDelay    = 200 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
+)
+
+// retryAWS calls fn, retrying with exponential backoff and full jitter while
+// it fails with a throttling or server error. It is safe to call on a nil
+// config.
+func (c *ClusterConfig) retryAWS(ctx context.Context, op string, fn func() error) error {
+	retries := defaultMaxRetries
+	if c != nil && c.MaxRetries != 0 {
+		retries = c.MaxRetries
+	}
+
+	err := fn()
+	for attempt := 0; attempt < retries && err != nil && isRetryableAWSError(err); attempt++ {
+		delay := retryDelay(attempt)
+		c.logger().WithField("attempt", attempt+1).Warnf("%s failed, retrying in %s: %s", op, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn()
+	}
+	return err
+}
+
+// noSDKRetries is a request option turning off the retries of the SDK, for
+// calls retried by retryAWS that would otherwise be retried by both.
+func noSDKRetries(r *request.Request) {
+	r.Retryer = client.NoOpRetryer{}
+}
+
+// retryDelay returns a random delay of up to base * 2^attempt.
+func retryDelay(attempt int) time.Duration {
+	max := baseRetryDelay << uint(attempt)
+	if max <= 0 || max > maxRetryDelay {
+		max = maxRetryDelay
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// isRetryableAWSError reports whether err is a throttling or 5xx error from
+// an AWS API.
+func isRetryableAWSError(err error) bool {
+	if request.IsErrorThrottle(err) {
+		return true
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		return reqErr.StatusCode() >= 500
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/pkg/errors"
+)
+
+func TestDescribeClusterRetriesThrottling(t *testing.T) {
+	api := newFakeAPIServer(t)
+	svc := newFakeEKS(testCluster(testClusterName, api.URL, api.caData()))
+	svc.errs = []error{
+		awsRequestFailure(http.StatusTooManyRequests, "ThrottlingException"),
+		awsRequestFailure(http.StatusTooManyRequests, "ThrottlingException"),
+	}
+	config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: svc, Logger: newTestLogger()}
+
+	if err := config.lookupCluster(context.Background()); err != nil {
+		t.Fatalf("lookupCluster() = %v", err)
+	}
+	if n := svc.calls(); n != 3 {
+		t.Errorf("DescribeCluster called %d times, want 3", n)
+	}
+	if config.MasterEndpoint != api.URL {
+		t.Errorf("MasterEndpoint = %q, want %q", config.MasterEndpoint, api.URL)
+	}
+}
+
+func TestDescribeClusterDoesNotRetryNotFound(t *testing.T) {
+	svc := newFakeEKS()
+	config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: svc, Logger: newTestLogger()}
+
+	err := config.lookupCluster(context.Background())
+	if !errors.Is(err, ErrClusterNotFound) {
+		t.Fatalf("lookupCluster() = %v, want %v", err, ErrClusterNotFound)
+	}
+	if n := svc.calls(); n != 1 {
+		t.Errorf("DescribeCluster called %d times, want 1", n)
+	}
+}
+
+func TestDescribeClusterRetriesExhausted(t *testing.T) {
+	svc := newFakeEKS()
+	for i := 0; i < 3; i++ {
+		svc.errs = append(svc.errs, awsRequestFailure(http.StatusInternalServerError, eks.ErrCodeServerException))
+	}
+	config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: svc, MaxRetries: 2, Logger: newTestLogger()}
+
+	if err := config.lookupCluster(context.Background()); err == nil {
+		t.Fatal("lookupCluster() = nil, want the server error")
+	}
+	if n := svc.calls(); n != 3 {
+		t.Errorf("DescribeCluster called %d times with MaxRetries 2, want 3", n)
+	}
+}
+
+func TestGetCallerIdentityRetriesThrottling(t *testing.T) {
+	for _, code := range []string{"Throttling", "RequestLimitExceeded"} {
+		t.Run(code, func(t *testing.T) {
+			fake := newFakeAWS()
+			config := testConfig(t, fake, nil)
+			fake.fail("GetCallerIdentity", 1, http.StatusBadRequest, code)
+
+			client, err := config.NewClientConfig()
+			if err != nil {
+				t.Fatalf("NewClientConfig() = %v", err)
+			}
+			if client.RoleARN() != testCallerARN {
+				t.Errorf("RoleARN() = %q, want %q", client.RoleARN(), testCallerARN)
+			}
+			if n := len(fake.callsTo("GetCallerIdentity")); n != 2 {
+				t.Errorf("GetCallerIdentity called %d times, want 2", n)
+			}
+		})
+	}
+}
+
+// sdkRetryingSession returns a session of fake whose clients retry with the
+// SDK's default retryer.
+func sdkRetryingSession(t *testing.T, fake *fakeAWS) *session.Session {
+	return fake.session(t).Copy(aws.NewConfig().WithMaxRetries(aws.UseServiceDefaultRetries))
+}
+
+func TestDescribeClusterNotRetriedBySDK(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.Session = sdkRetryingSession(t, fake)
+	config.MaxRetries = 1
+	fake.fail("DescribeCluster", 10, http.StatusInternalServerError, eks.ErrCodeServerException)
+
+	if err := config.lookupCluster(context.Background()); err == nil {
+		t.Fatal("lookupCluster() = nil, want the server error")
+	}
+	if n := len(fake.callsTo("DescribeCluster")); n != 2 {
+		t.Errorf("DescribeCluster called %d times with MaxRetries 1, want 2", n)
+	}
+}
+
+func TestGetCallerIdentityNotRetriedBySDK(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.Session = sdkRetryingSession(t, fake)
+	config.MaxRetries = 1
+	fake.fail("GetCallerIdentity", 10, http.StatusBadRequest, "Throttling")
+
+	if _, err := config.NewClientConfig(); err == nil {
+		t.Fatal("NewClientConfig() = nil, want the throttling error")
+	}
+	if n := len(fake.callsTo("GetCallerIdentity")); n != 2 {
+		t.Errorf("GetCallerIdentity called %d times with MaxRetries 1, want 2", n)
+	}
+}
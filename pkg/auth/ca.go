@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CertPool returns a certificate pool containing the cluster certificate
+// authority and AdditionalCABundle.
+func (c *ClusterConfig) CertPool() (*x509.CertPool, error) {
+	data, err := c.caBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no valid PEM certificates found in certificate authority data")
+	}
+	return pool, nil
+}
+
+// CAPem returns the cluster certificate authority as PEM text.
+func (c *ClusterConfig) CAPem() (string, error) {
+	data, err := c.decodeCA()
+	if err != nil {
+		return "", err
+	}
+	if block, _ := pem.Decode(data); block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.New("certificate authority data is not a PEM certificate")
+	}
+	return string(data), nil
+}
+
+// loadCAFile sets CertificateAuthorityData from CAFile if it is empty.
+func (c *ClusterConfig) loadCAFile() error {
+	if c.CAFile == "" || c.CertificateAuthorityData != "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return errors.Wrap(err, "reading CAFile")
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return errors.Errorf("no valid PEM certificates found in %s", c.CAFile)
+	}
+	c.CertificateAuthorityData = base64.StdEncoding.EncodeToString(data)
+	return nil
+}
+
+// decodeCA decodes CertificateAuthorityData, ignoring whitespace such as the
+// line breaks added when the data is copied through other tools, with or
+// without padding.
+func (c *ClusterConfig) decodeCA() ([]byte, error) {
+	encoded := strings.Join(strings.Fields(c.CertificateAuthorityData), "")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err == nil {
+		return data, nil
+	}
+	if data, rawErr := base64.RawStdEncoding.DecodeString(encoded); rawErr == nil {
+		return data, nil
+	}
+	return nil, errors.Wrap(err, "decoding certificate authority data: not valid base64")
+}
+
+// caBundle returns the decoded cluster CA followed by AdditionalCABundle.
+func (c *ClusterConfig) caBundle() ([]byte, error) {
+	data, err := c.decodeCA()
+	if err != nil || len(c.AdditionalCABundle) == 0 {
+		return data, err
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(c.AdditionalCABundle) {
+		return nil, errors.New("no valid PEM certificates found in AdditionalCABundle")
+	}
+	bundle := make([]byte, 0, len(data)+1+len(c.AdditionalCABundle))
+	bundle = append(bundle, data...)
+	if len(bundle) > 0 && bundle[len(bundle)-1] != '\n' {
+		bundle = append(bundle, '\n')
+	}
+	return append(bundle, c.AdditionalCABundle...), nil
+}
+
+// Endpoint returns the API server URL of the cluster.
+func (c *ClientConfig) Endpoint() string {
+	cluster := c.currentCluster()
+	if cluster == nil {
+		return ""
+	}
+	return cluster.Server
+}
+
+// CACertificate returns the decoded PEM certificate authority of the cluster,
+// followed by AdditionalCABundle if set.
+// The returned slice must not be modified.
+func (c *ClientConfig) CACertificate() []byte {
+	cluster := c.currentCluster()
+	if cluster == nil {
+		return nil
+	}
+	return cluster.CertificateAuthorityData
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// lookedUpClientConfig returns a client config for a cluster served by api.
+func lookedUpClientConfig(t *testing.T, config *ClusterConfig) *ClientConfig {
+	t.Helper()
+	if err := config.lookupCluster(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestEndpointAndCACertificate(t *testing.T) {
+	api := newFakeAPIServer(t)
+	client := lookedUpClientConfig(t, testConfig(t, nil, api))
+
+	if got := client.Endpoint(); got != api.URL {
+		t.Errorf("Endpoint() = %q, want %q", got, api.URL)
+	}
+	if got := client.CACertificate(); !bytes.Equal(got, api.caPEM()) {
+		t.Errorf("CACertificate() = %q, want the decoded PEM %q", got, api.caPEM())
+	}
+
+	empty := &ClientConfig{Client: clientcmdapi.NewConfig()}
+	if empty.Endpoint() != "" || empty.CACertificate() != nil {
+		t.Error("accessors of a config without a cluster are not empty")
+	}
+}
+
+func TestAdditionalCABundle(t *testing.T) {
+	api := newFakeAPIServer(t)
+	proxyCA := testCAPEM(t, "proxy CA")
+	config := testConfig(t, nil, api)
+	config.AdditionalCABundle = proxyCA
+	client := lookedUpClientConfig(t, config)
+
+	data := client.CACertificate()
+	var subjects []string
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, cert.Subject.CommonName)
+	}
+	if !bytes.HasPrefix(data, api.caPEM()) || !bytes.HasSuffix(data, proxyCA) || len(subjects) != 2 {
+		t.Errorf("CA data holds %q, want the cluster CA followed by the proxy CA", subjects)
+	}
+
+	config.AdditionalCABundle = []byte("not a certificate")
+	if _, err := config.NewClientConfig(); err == nil {
+		t.Error("NewClientConfig() = nil error for an invalid AdditionalCABundle")
+	}
+}
+
+// testCAPEM returns a self-signed CA certificate as PEM.
+func testCAPEM(t *testing.T, commonName string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCAPem(t *testing.T) {
+	caPEM := testCAPEM(t, "cluster CA")
+	config := &ClusterConfig{CertificateAuthorityData: base64.StdEncoding.EncodeToString(caPEM)}
+
+	got, err := config.CAPem()
+	if err != nil {
+		t.Fatalf("CAPem() = %v", err)
+	}
+	if !strings.HasPrefix(got, "-----BEGIN CERTIFICATE-----\n") || got != string(caPEM) {
+		t.Errorf("CAPem() = %q, want %q", got, caPEM)
+	}
+
+	config.CertificateAuthorityData = base64.StdEncoding.EncodeToString([]byte("not a certificate"))
+	if _, err := config.CAPem(); err == nil {
+		t.Error("CAPem() = nil error for data that is not PEM")
+	}
+	config.CertificateAuthorityData = "not base64!"
+	if _, err := config.CAPem(); err == nil {
+		t.Error("CAPem() = nil error for data that is not base64")
+	}
+}
+
+func TestCAFile(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	config := &ClusterConfig{
+		ClusterName:    testClusterName,
+		MasterEndpoint: api.URL,
+		CAFile:         writeTestFile(t, "ca.crt", api.caPEM()),
+		Session:        fake.session(t),
+		Logger:         newTestLogger(),
+	}
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	if calls := fake.callsTo("DescribeCluster"); len(calls) != 0 {
+		t.Errorf("DescribeCluster called %d times with the endpoint and CA file given", len(calls))
+	}
+	if config.CertificateAuthorityData != api.caData() {
+		t.Errorf("CertificateAuthorityData = %q, want the CA file contents", config.CertificateAuthorityData)
+	}
+
+	config.CertificateAuthorityData = ""
+	config.CAFile = writeTestFile(t, "invalid.crt", []byte("not a certificate"))
+	if _, err := NewAuthClient(config); err == nil {
+		t.Error("NewAuthClient() = nil error for a CA file without certificates")
+	}
+}
+
+func TestCertificateAuthorityDataWhitespace(t *testing.T) {
+	caPEM := testCAPEM(t, "cluster CA")
+	encoded := base64.StdEncoding.EncodeToString(caPEM)
+	var wrapped strings.Builder
+	for i := 0; i < len(encoded); i += 64 {
+		end := i + 64
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		wrapped.WriteString(encoded[i:end] + "\r\n")
+	}
+
+	tests := map[string]string{
+		"line breaks": wrapped.String(),
+		"spaces":      " " + strings.Replace(encoded, "A", "A ", 3) + "\t",
+		"no padding":  strings.TrimRight(encoded, "=") + "\n",
+		"unchanged":   encoded,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &ClusterConfig{
+				ClusterName:              testClusterName,
+				MasterEndpoint:           "https://test.eks.amazonaws.com",
+				CertificateAuthorityData: data,
+				Session:                  newFakeAWS().session(t),
+				SkipCallerIdentity:       true,
+				Logger:                   newTestLogger(),
+			}
+			client, err := config.NewClientConfig()
+			if err != nil {
+				t.Fatalf("NewClientConfig() = %v", err)
+			}
+			if got := client.CACertificate(); !bytes.Equal(got, caPEM) {
+				t.Errorf("CACertificate() = %q, want %q", got, caPEM)
+			}
+		})
+	}
+
+	config := &ClusterConfig{ClusterName: testClusterName, CertificateAuthorityData: "not base64!", SkipCallerIdentity: true, Session: newFakeAWS().session(t), Logger: newTestLogger()}
+	if _, err := config.NewClientConfig(); err == nil || !strings.Contains(err.Error(), "not valid base64") {
+		t.Errorf("NewClientConfig() = %v, want a base64 error", err)
+	}
+}
+
+func TestCertPool(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := testConfig(t, nil, api)
+	if err := config.lookupCluster(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := config.CertPool()
+	if err != nil {
+		t.Fatalf("CertPool() = %v", err)
+	}
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := httpClient.Get(api.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz with the pool = %v", err)
+	}
+	resp.Body.Close()
+
+	proxyCA := testCAPEM(t, "proxy CA")
+	config.AdditionalCABundle = proxyCA
+	if pool, err = config.CertPool(); err != nil {
+		t.Fatalf("CertPool() with AdditionalCABundle = %v", err)
+	}
+	block, _ := pem.Decode(proxyCA)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Errorf("AdditionalCABundle certificate not in the pool: %v", err)
+	}
+
+	config.AdditionalCABundle = nil
+	config.CertificateAuthorityData = base64.StdEncoding.EncodeToString([]byte("not a certificate"))
+	if _, err := config.CertPool(); err == nil {
+		t.Error("CertPool() = nil error for CA data without certificates")
+	}
+}
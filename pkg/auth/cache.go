@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
+)
+
+// Cached tokens are not used when they expire within this window, unless
+// ClientConfig.TokenExpirySkew says otherwise.
+const defaultTokenExpirySkew = time.Minute
+
+// TokenCache stores tokens. Keys identify the region and name of the cluster
+// and the identity the token was generated for.
+type TokenCache interface {
+	Get(key string) (token.Token, bool)
+	Set(key string, tok token.Token) error
+}
+
+// maxTokenTTL is how long STS accepts a presigned token after signing.
+const maxTokenTTL = 15 * time.Minute
+
+// tokenCacheTTL returns the configured TokenCacheTTL, or zero for the
+// generator default. It is safe to call on a nil config.
+func (c *ClusterConfig) tokenCacheTTL() (time.Duration, error) {
+	if c == nil {
+		return 0, nil
+	}
+	if c.TokenCacheTTL < 0 || c.TokenCacheTTL > maxTokenTTL {
+		return 0, errors.Errorf("TokenCacheTTL %s out of range, must be between 0 and %s", c.TokenCacheTTL, maxTokenTTL)
+	}
+	return c.TokenCacheTTL, nil
+}
+
+// tokenCacheKey identifies the cluster and the identity of its tokens, so
+// that same-named clusters in other regions or accounts, or other roles,
+// never share a cached token. The context name is no good for this as it
+// need not include either.
+func (c *ClientConfig) tokenCacheKey() string {
+	var region string
+	identity := c.roleARN
+	if c.cluster != nil && c.cluster.Session != nil {
+		region = aws.StringValue(c.cluster.Session.Config.Region)
+		if identity == "" && c.cluster.Session.Config.Credentials != nil {
+			// The role is unknown with SkipCallerIdentity, so tell identities
+			// apart by the access key signing the token.
+			if creds, err := c.cluster.Session.Config.Credentials.Get(); err == nil {
+				identity = creds.AccessKeyID
+			}
+		}
+	}
+	return region + "/" + c.ClusterName + "/" + identity
+}
+
+func tokenValid(tok token.Token, skew time.Duration) bool {
+	return tok.Token != "" && time.Now().Add(skew).Before(tok.Expiration)
+}
+
+// MemoryTokenCache is a TokenCache that keeps tokens in memory for the
+// lifetime of the process. It is safe for concurrent use.
+type MemoryTokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]token.Token
+}
+
+// NewMemoryTokenCache creates an empty in-memory token cache.
+func NewMemoryTokenCache() *MemoryTokenCache {
+	return &MemoryTokenCache{tokens: map[string]token.Token{}}
+}
+
+func (m *MemoryTokenCache) Get(key string) (token.Token, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tok, ok := m.tokens[key]
+	return tok, ok
+}
+
+func (m *MemoryTokenCache) Set(key string, tok token.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[key] = tok
+	return nil
+}
+
+// FileTokenCache is a TokenCache that persists each token to its own file
+// under Dir, readable only by the current user.
+type FileTokenCache struct {
+	Dir string
+}
+
+// NewFileTokenCache creates a file-backed token cache in dir. When dir is
+// empty, ~/.kube/cache/eksutil is used.
+func NewFileTokenCache(dir string) (*FileTokenCache, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.Wrap(err, "finding home directory for token cache")
+		}
+		dir = filepath.Join(home, ".kube", "cache", "eksutil")
+	}
+	return &FileTokenCache{Dir: dir}, nil
+}
+
+type cachedToken struct {
+	Token      string    `json:"token"`
+	Expiration time.Time `json:"expiration"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (f *FileTokenCache) path(key string) string {
+	return filepath.Join(f.Dir, unsafeFileChars.ReplaceAllString(key, "_")+".json")
+}
+
+// Get returns the cached token for key. Unreadable, malformed or expired
+// entries are treated as a cache miss.
+func (f *FileTokenCache) Get(key string) (token.Token, bool) {
+	b, err := ioutil.ReadFile(f.path(key))
+	if err != nil {
+		return token.Token{}, false
+	}
+
+	var c cachedToken
+	if err := json.Unmarshal(b, &c); err != nil {
+		return token.Token{}, false
+	}
+
+	tok := token.Token{Token: c.Token, Expiration: c.Expiration}
+	if !tokenValid(tok, defaultTokenExpirySkew) {
+		return token.Token{}, false
+	}
+	return tok, true
+}
+
+func (f *FileTokenCache) Set(key string, tok token.Token) error {
+	b, err := json.Marshal(cachedToken{Token: tok.Token, Expiration: tok.Expiration})
+	if err != nil {
+		return errors.Wrap(err, "encoding token")
+	}
+	return writeFileAtomic(f.path(key), b, 0600)
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
+)
+
+func TestMemoryTokenCache(t *testing.T) {
+	cache := NewMemoryTokenCache()
+	if _, ok := cache.Get("us-west-2/test/role"); ok {
+		t.Fatal("Get() on an empty cache found a token")
+	}
+
+	tok := token.Token{Token: "k8s-aws-v1.abc", Expiration: time.Now().Add(10 * time.Minute)}
+	if err := cache.Set("us-west-2/test/role", tok); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := cache.Get("us-west-2/test/role"); !ok || got != tok {
+		t.Errorf("Get() = %v, %t, want %v", got, ok, tok)
+	}
+}
+
+func TestFileTokenCache(t *testing.T) {
+	cache, err := NewFileTokenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "us-west-2/test/arn:aws:iam::111122223333:role/Admin"
+	tok := token.Token{Token: "k8s-aws-v1.abc", Expiration: time.Now().Add(10 * time.Minute).Round(0)}
+	if err := cache.Set(key, tok); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := cache.Get(key)
+	if !ok || got.Token != tok.Token || !got.Expiration.Equal(tok.Expiration) {
+		t.Errorf("Get() = %v, %t, want %v", got, ok, tok)
+	}
+
+	files, err := ioutil.ReadDir(cache.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("cache dir holds %d files, want 1", len(files))
+	}
+	if mode := files[0].Mode().Perm(); mode != 0600 {
+		t.Errorf("cache file mode = %o, want 600", mode)
+	}
+}
+
+func TestFileTokenCacheInvalidEntries(t *testing.T) {
+	cache, err := NewFileTokenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired := token.Token{Token: "k8s-aws-v1.old", Expiration: time.Now().Add(-time.Minute)}
+	if err := cache.Set("expired", expired); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(cache.Dir, "malformed.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"expired", "malformed", "missing"} {
+		if tok, ok := cache.Get(key); ok {
+			t.Errorf("Get(%q) = %v, want a miss", key, tok)
+		}
+	}
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	fake := newFakeAWS()
+	sess := fake.session(t)
+	west := &ClientConfig{ClusterName: "test", roleARN: "arn:aws:iam::111122223333:role/Admin", cluster: &ClusterConfig{Session: sess}}
+	east := &ClientConfig{ClusterName: "test", roleARN: west.roleARN, cluster: &ClusterConfig{Session: sess.Copy(aws.NewConfig().WithRegion("us-east-1"))}}
+	other := &ClientConfig{ClusterName: "test", roleARN: "arn:aws:iam::444455556666:role/Admin", cluster: west.cluster}
+	unknown := &ClientConfig{ClusterName: "test", cluster: west.cluster}
+
+	if got, want := west.tokenCacheKey(), "us-west-2/test/arn:aws:iam::111122223333:role/Admin"; got != want {
+		t.Errorf("tokenCacheKey() = %q, want %q", got, want)
+	}
+	if west.tokenCacheKey() == east.tokenCacheKey() {
+		t.Error("clusters in different regions share a cache key")
+	}
+	if west.tokenCacheKey() == other.tokenCacheKey() {
+		t.Error("roles in different accounts share a cache key")
+	}
+	if got, want := unknown.tokenCacheKey(), "us-west-2/test/"+testAccessKeyID; got != want {
+		t.Errorf("tokenCacheKey() without a role = %q, want %q", got, want)
+	}
+}
+
+func TestTokenCacheReusesTokens(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.TokenCache = NewMemoryTokenCache()
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := &fakeTokenGenerator{}
+	client.TokenGenerator = gen
+
+	first, _, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := client.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || gen.generated() != 1 {
+		t.Errorf("got tokens %q and %q from %d generations, want one cached token", first, second, gen.generated())
+	}
+	if _, ok := config.TokenCache.Get(client.tokenCacheKey()); !ok {
+		t.Error("token not stored under the cache key")
+	}
+}
+
+func TestNewFileTokenCacheDefaultDir(t *testing.T) {
+	home := t.TempDir()
+	setEnv(t, "HOME", home)
+
+	cache, err := NewFileTokenCache("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".kube", "cache", "eksutil"); cache.Dir != want {
+		t.Errorf("Dir = %q, want %q", cache.Dir, want)
+	}
+	if err := cache.Set("key", token.Token{Token: "t", Expiration: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cache.Dir); err != nil {
+		t.Errorf("cache dir not created: %v", err)
+	}
+}
+
+func TestTokenCacheTTL(t *testing.T) {
+	tests := []struct {
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{ttl: 0},
+		{ttl: 5 * time.Minute},
+		{ttl: 20 * time.Minute, wantErr: true},
+		{ttl: -time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ttl.String(), func(t *testing.T) {
+			config := testConfig(t, nil, nil)
+			config.TokenCacheTTL = tt.ttl
+			client, err := config.NewClientConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.TokenGenerator = &fakeTokenGenerator{}
+
+			_, expiry, err := client.Token()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Token() = nil error for an out of range TokenCacheTTL")
+				}
+				if config.Validate() == nil {
+					t.Error("Validate() = nil for an out of range TokenCacheTTL")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := time.Now().Add(15 * time.Minute)
+			if tt.ttl > 0 {
+				want = time.Now().Add(tt.ttl)
+			}
+			if d := want.Sub(expiry); d < 0 || d > time.Minute {
+				t.Errorf("expiry in %s, want %s", time.Until(expiry).Round(time.Second), time.Until(want).Round(time.Second))
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when a token is requested from a client config whose
+// cluster config has been closed.
+var ErrClosed = errors.New("cluster config is closed")
+
+// Close releases the session and cached credentials of the config. Client
+// configs created from it can no longer generate tokens, while clientsets
+// already created keep working until their token expires. Close is safe to
+// call more than once.
+func (c *ClusterConfig) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	if c.Session != nil && c.Session.Config.Credentials != nil {
+		c.Session.Config.Credentials.Expire()
+	}
+	c.Session = nil
+	return nil
+}
+
+// checkOpen returns ErrClosed after Close. It is safe to call on a nil
+// config.
+func (c *ClusterConfig) checkOpen() error {
+	if c != nil && atomic.LoadInt32(&c.closed) != 0 {
+		return ErrClosed
+	}
+	return nil
+}
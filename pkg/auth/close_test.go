@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestClose(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	if err := config.Close(); err != nil {
+		t.Fatalf("second Close() = %v", err)
+	}
+
+	if _, _, err := client.Token(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Token() after Close = %v, want %v", err, ErrClosed)
+	}
+	if _, err := client.WithCachedToken(); !errors.Is(err, ErrClosed) {
+		t.Errorf("WithCachedToken() after Close = %v, want %v", err, ErrClosed)
+	}
+	if _, err := NewAuthClient(config); !errors.Is(err, ErrClosed) {
+		t.Errorf("NewAuthClient() after Close = %v, want %v", err, ErrClosed)
+	}
+}
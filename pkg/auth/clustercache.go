@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// DefaultCacheTTL is a reasonable CacheTTL for long running processes.
+const DefaultCacheTTL = 10 * time.Minute
+
+type clusterCacheEntry struct {
+	endpoint string
+	ca       string
+	version  string
+	public   bool
+	private  bool
+	expires  time.Time
+}
+
+// clusterCache holds cluster lookups shared by all configs with a CacheTTL.
+var clusterCache = struct {
+	sync.Mutex
+	entries map[string]clusterCacheEntry
+}{entries: make(map[string]clusterCacheEntry)}
+
+// lookupCluster is like loadConfig, but uses the endpoint and CA given by the
+// caller if both are set, and otherwise reuses a cached lookup of the same
+// cluster that is younger than CacheTTL.
+func (c *ClusterConfig) lookupCluster(ctx context.Context) error {
+	if err := c.resolveClusterName(); err != nil {
+		return err
+	}
+	if err := c.loadCAFile(); err != nil {
+		return err
+	}
+	if c.lookupDisabled() {
+		c.logger().WithField("cluster", c.clusterID()).Debugf("Using given endpoint and CA, skipping cluster lookup")
+		return nil
+	}
+
+	if c.CacheTTL <= 0 {
+		return c.loadConfig(ctx)
+	}
+
+	key := c.clusterCacheKey(ctx)
+	clusterCache.Lock()
+	entry, ok := clusterCache.entries[key]
+	clusterCache.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		c.logger().WithField("cluster", c.clusterID()).Debugf("Using cached cluster lookup")
+		c.MasterEndpoint = entry.endpoint
+		c.CertificateAuthorityData = entry.ca
+		c.KubernetesVersion = entry.version
+		c.EndpointPublicAccess = entry.public
+		c.EndpointPrivateAccess = entry.private
+		c.lookedUp = true
+		return nil
+	}
+	return c.loadConfig(ctx)
+}
+
+// lookupDisabled reports whether the caller gave both the endpoint and the
+// CA, so that the cluster is not looked up.
+func (c *ClusterConfig) lookupDisabled() bool {
+	return !c.lookedUp && c.MasterEndpoint != "" && c.CertificateAuthorityData != ""
+}
+
+// cacheCluster stores the results of the last lookup if caching is enabled.
+func (c *ClusterConfig) cacheCluster(ctx context.Context) {
+	if c.CacheTTL <= 0 {
+		return
+	}
+
+	key := c.clusterCacheKey(ctx)
+	clusterCache.Lock()
+	defer clusterCache.Unlock()
+	clusterCache.entries[key] = clusterCacheEntry{
+		endpoint: c.MasterEndpoint,
+		ca:       c.CertificateAuthorityData,
+		version:  c.KubernetesVersion,
+		public:   c.EndpointPublicAccess,
+		private:  c.EndpointPrivateAccess,
+		expires:  time.Now().Add(c.CacheTTL),
+	}
+}
+
+// clusterCacheKey identifies the cluster along with everything deciding which
+// account it is looked up in, since clusters in different accounts may have
+// the same name. The access key of the session is part of it, as a Session
+// given by the caller may be for any account.
+func (c *ClusterConfig) clusterCacheKey(ctx context.Context) string {
+	var region string
+	if c.Session != nil {
+		region = aws.StringValue(c.Session.Config.Region)
+	}
+	return strings.Join([]string{
+		region,
+		c.EKSEndpoint,
+		c.AWSEndpoint,
+		c.Profile,
+		strings.Join(c.assumeRoleChain(), ","),
+		c.accessKeyID(ctx),
+		c.ClusterName,
+	}, "/")
+}
+
+// accessKeyID returns the access key of the session credentials, or an empty
+// string if they cannot be retrieved, in which case the lookup fails too.
+func (c *ClusterConfig) accessKeyID(ctx context.Context) string {
+	if c.Session == nil || c.Session.Config.Credentials == nil {
+		return ""
+	}
+	creds, err := c.Session.Config.Credentials.GetWithContext(ctx)
+	if err != nil {
+		return ""
+	}
+	return creds.AccessKeyID
+}
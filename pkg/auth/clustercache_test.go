@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func cachedLookupConfig(t *testing.T, svc *fakeEKS, ttl time.Duration) *ClusterConfig {
+	return &ClusterConfig{
+		ClusterName: testClusterName,
+		Session:     newFakeAWS().session(t),
+		EKS:         svc,
+		CacheTTL:    ttl,
+		Logger:      newTestLogger(),
+	}
+}
+
+func TestClusterCacheHit(t *testing.T) {
+	resetClusterCache(t)
+	svc := newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E="))
+
+	for i := 0; i < 3; i++ {
+		config := cachedLookupConfig(t, svc, time.Minute)
+		if err := config.lookupCluster(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if config.MasterEndpoint != "https://test.eks.amazonaws.com" || config.KubernetesVersion != "1.17" {
+			t.Errorf("lookup %d: endpoint %q, version %q", i, config.MasterEndpoint, config.KubernetesVersion)
+		}
+	}
+	if n := svc.calls(); n != 1 {
+		t.Errorf("DescribeCluster called %d times, want 1", n)
+	}
+}
+
+func TestClusterCacheExpiry(t *testing.T) {
+	resetClusterCache(t)
+	svc := newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E="))
+
+	for i := 0; i < 2; i++ {
+		if err := cachedLookupConfig(t, svc, 20*time.Millisecond).lookupCluster(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+	if n := svc.calls(); n != 2 {
+		t.Errorf("DescribeCluster called %d times, want 2 after the entry expired", n)
+	}
+}
+
+func TestClusterCacheKeyedByRoleChain(t *testing.T) {
+	resetClusterCache(t)
+	svc := newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E="))
+
+	for _, roleARN := range []string{"", "arn:aws:iam::444455556666:role/EKSAdmin", "arn:aws:iam::777788889999:role/EKSAdmin"} {
+		config := cachedLookupConfig(t, svc, time.Minute)
+		config.AssumeRoleARN = roleARN
+		if err := config.lookupCluster(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := svc.calls(); n != 3 {
+		t.Errorf("DescribeCluster called %d times for 3 identities, want 3", n)
+	}
+}
+
+func TestClusterCacheKeyedByCredentials(t *testing.T) {
+	resetClusterCache(t)
+	svc := newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E="))
+
+	// Sessions given by the caller may be for different accounts even with
+	// the same region, profile and roles.
+	for _, accessKeyID := range []string{testAccessKeyID, "AKIDOTHERACCOUNT", testAccessKeyID} {
+		config := cachedLookupConfig(t, svc, time.Minute)
+		config.Session = config.Session.Copy(aws.NewConfig().
+			WithCredentials(credentials.NewStaticCredentials(accessKeyID, "secret", "")))
+		if err := config.lookupCluster(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := svc.calls(); n != 2 {
+		t.Errorf("DescribeCluster called %d times for 2 access keys, want 2", n)
+	}
+}
+
+func TestClusterCacheConcurrent(t *testing.T) {
+	resetClusterCache(t)
+	svc := newFakeEKS(testCluster(testClusterName, "https://test.eks.amazonaws.com", "Y2E="))
+	sess := newFakeAWS().session(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := &ClusterConfig{ClusterName: testClusterName, Session: sess, EKS: svc, CacheTTL: time.Minute, Logger: newTestLogger()}
+			if err := config.lookupCluster(context.Background()); err != nil {
+				errs <- err
+				return
+			}
+			if config.MasterEndpoint != "https://test.eks.amazonaws.com" {
+				errs <- fmt.Errorf("endpoint %q", config.MasterEndpoint)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
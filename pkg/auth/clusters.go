@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/pkg/errors"
+)
+
+// ListClusters returns the names of the EKS clusters in the region of the
+// session, e.g. to let the user pick one when no ClusterName is given. It
+// requires eks:ListClusters. Use the ClusterConfig method to apply options
+// such as EKSEndpoint or UseFIPSEndpoints.
+func ListClusters(sess *session.Session) ([]string, error) {
+	return (&ClusterConfig{Session: sess}).ListClusters()
+}
+
+// ListClusters returns the names of the EKS clusters in the region of the
+// config, ignoring ClusterName. It requires eks:ListClusters.
+func (c *ClusterConfig) ListClusters() ([]string, error) {
+	if err := c.ensureSession(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err := c.eksAPI().ListClustersPages(&eks.ListClustersInput{}, func(page *eks.ListClustersOutput, lastPage bool) bool {
+		names = append(names, aws.StringValueSlice(page.Clusters)...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing EKS clusters")
+	}
+	return names, nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListClusters(t *testing.T) {
+	fake := newFakeAWS()
+	fake.pageSize = 1
+	fake.addCluster("prod", "https://prod.eks.amazonaws.com", "Y2E=")
+	fake.addCluster("staging", "https://staging.eks.amazonaws.com", "Y2E=")
+
+	names, err := ListClusters(fake.session(t))
+	if err != nil {
+		t.Fatalf("ListClusters() = %v", err)
+	}
+	if want := []string{"prod", "staging"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListClusters() = %q, want %q", names, want)
+	}
+	calls := fake.callsTo("ListClusters")
+	if len(calls) != 2 || calls[1].Params.Get("nextToken") != "1" {
+		t.Errorf("ListClusters calls = %+v, want two pages", calls)
+	}
+}
+
+func TestListClustersError(t *testing.T) {
+	fake := newFakeAWS()
+	fake.fail("ListClusters", 1, http.StatusForbidden, "AccessDeniedException")
+
+	if names, err := ListClusters(fake.session(t)); err == nil {
+		t.Errorf("ListClusters() = %q, want an error", names)
+	}
+}
+
+func TestClusterConfigListClusters(t *testing.T) {
+	fake := newFakeAWS()
+	fake.addCluster("prod", "https://prod.eks.amazonaws.com", "Y2E=")
+	config := &ClusterConfig{
+		Region:      testRegion,
+		Session:     fake.session(t),
+		EKSEndpoint: "https://vpce-eks.example.com",
+		UserAgent:   "deployer/1.2.3",
+		Logger:      newTestLogger(),
+	}
+
+	names, err := config.ListClusters()
+	if err != nil {
+		t.Fatalf("ListClusters() = %v", err)
+	}
+	if want := []string{"prod"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListClusters() = %q, want %q", names, want)
+	}
+	calls := fake.callsTo("ListClusters")
+	if len(calls) != 1 || calls[0].Host != "vpce-eks.example.com" {
+		t.Fatalf("ListClusters calls = %+v, want one to EKSEndpoint", calls)
+	}
+	if !strings.Contains(calls[0].UserAgent, "deployer/1.2.3") {
+		t.Errorf("ListClusters User-Agent = %q, want it to include UserAgent", calls[0].UserAgent)
+	}
+}
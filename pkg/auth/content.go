@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/transport"
+)
+
+const protobufAccept = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+
+// contentTypeLogger returns a transport wrapper that inspects the content type
+// of the first successful response and logs if it is not protobuf.
+func contentTypeLogger(logger Logger) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &contentTypeRoundTripper{logger: logger, rt: rt}
+	}
+}
+
+type contentTypeRoundTripper struct {
+	logger Logger
+	rt     http.RoundTripper
+	once   sync.Once
+}
+
+func (t *contentTypeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil || resp.StatusCode >= 300 {
+		return resp, err
+	}
+
+	t.once.Do(func() {
+		contentType := resp.Header.Get("Content-Type")
+		logger := t.logger.WithField("contentType", contentType).WithField("path", req.URL.Path)
+		if strings.HasPrefix(contentType, runtime.ContentTypeProtobuf) {
+			logger.Debugf("API server negotiated protobuf")
+			return
+		}
+		logger.Warnf("Protobuf requested but API server responded with a different content type")
+	})
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestUseProtobuf(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.ContentType != "" || restConfig.AcceptContentTypes != "" {
+		t.Errorf("default content types = %q, %q, want the client-go defaults", restConfig.ContentType, restConfig.AcceptContentTypes)
+	}
+
+	config.UseProtobuf = true
+	if restConfig, err = client.NewRESTConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.ContentType != runtime.ContentTypeProtobuf {
+		t.Errorf("ContentType = %q, want %q", restConfig.ContentType, runtime.ContentTypeProtobuf)
+	}
+	if want := "application/vnd.kubernetes.protobuf,application/json"; restConfig.AcceptContentTypes != want {
+		t.Errorf("AcceptContentTypes = %q, want %q", restConfig.AcceptContentTypes, want)
+	}
+}
+
+func TestLogContentTypeFallback(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.UseProtobuf = true
+	config.LogContentTypeFallback = true
+	logger := config.Logger.(*testLogger)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	cs, err := client.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test API server only speaks JSON.
+	if _, err := cs.CoreV1().Namespaces().List(metav1.ListOptions{}); err != nil {
+		t.Fatalf("List() = %v", err)
+	}
+	entry, ok := logger.find("warn", "API server responded with a different content type")
+	if !ok {
+		t.Fatal("JSON response to a protobuf client not logged")
+	}
+	if entry.Fields["contentType"] != "application/json" || entry.Fields["cluster"] != testClusterName {
+		t.Errorf("log fields = %v, want the content type and cluster", entry.Fields)
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper answering with a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestContentTypeLogger(t *testing.T) {
+	tests := []struct {
+		name        string
+		responses   []*http.Response
+		level       string
+		wantEntries int
+	}{
+		{
+			name:        "protobuf",
+			responses:   []*http.Response{contentTypeResponse(200, runtime.ContentTypeProtobuf)},
+			level:       "debug",
+			wantEntries: 1,
+		},
+		{
+			name:        "JSON fallback",
+			responses:   []*http.Response{contentTypeResponse(200, runtime.ContentTypeJSON), contentTypeResponse(200, runtime.ContentTypeJSON)},
+			level:       "warn",
+			wantEntries: 1,
+		},
+		{
+			name:        "error responses are skipped",
+			responses:   []*http.Response{contentTypeResponse(404, runtime.ContentTypeJSON), contentTypeResponse(200, runtime.ContentTypeProtobuf)},
+			level:       "debug",
+			wantEntries: 1,
+		},
+		{
+			name:      "errors only",
+			responses: []*http.Response{contentTypeResponse(500, runtime.ContentTypeJSON)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newTestLogger()
+			responses := tt.responses
+			rt := contentTypeLogger(logger)(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				resp := responses[0]
+				responses = responses[1:]
+				return resp, nil
+			}))
+
+			for range tt.responses {
+				req, err := http.NewRequest(http.MethodGet, "https://cluster.example.com/api/v1/pods", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := rt.RoundTrip(req); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(logger.log.entries) != tt.wantEntries {
+				t.Fatalf("logged %v, want %d entries", logger.log.entries, tt.wantEntries)
+			}
+			if tt.wantEntries > 0 {
+				entry := logger.log.entries[0]
+				if entry.Level != tt.level || entry.Fields["path"] != "/api/v1/pods" {
+					t.Errorf("logged %+v, want a %s entry with the request path", entry, tt.level)
+				}
+			}
+		})
+	}
+}
+
+func contentTypeResponse(status int, contentType string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+}
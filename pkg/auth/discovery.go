@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type resourcesHolder struct {
+	mu        sync.Mutex
+	resources []*metav1.APIResourceList
+}
+
+// ServerResources returns the API resources supported by the cluster, for
+// all groups and versions. The result is fetched on the first call and
+// reused by later calls on the config and its copies, unless some API groups
+// could not be discovered, in which case the partial result is returned with
+// the error.
+func (c *ClientConfig) ServerResources() ([]*metav1.APIResourceList, error) {
+	if c.resources == nil {
+		c.resources = &resourcesHolder{}
+	}
+
+	c.resources.mu.Lock()
+	defer c.resources.mu.Unlock()
+
+	if c.resources.resources != nil {
+		return c.resources.resources, nil
+	}
+
+	cs, err := c.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		return nil, err
+	}
+	_, resources, err := cs.Discovery().ServerGroupsAndResources()
+	if err != nil {
+		return resources, errors.Wrap(err, "discovering API resources")
+	}
+
+	c.resources.resources = resources
+	return resources, nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestServerResources(t *testing.T) {
+	api := newFakeAPIServer(t)
+	client := lookedUpClientConfig(t, testConfig(t, nil, api))
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	resources, err := client.ServerResources()
+	if err != nil {
+		t.Fatalf("ServerResources() = %v", err)
+	}
+	var got []string
+	for _, list := range resources {
+		for _, r := range list.APIResources {
+			got = append(got, list.GroupVersion+"/"+r.Name)
+		}
+	}
+	sort.Strings(got)
+	if want := []string{"apps/v1/deployments", "v1/pods"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerResources() = %q, want %q", got, want)
+	}
+
+	requests := len(api.requestTokens())
+	if _, err := client.DeepCopy().ServerResources(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(api.requestTokens()); n != requests {
+		t.Errorf("%d more discovery requests, want the cached result", n-requests)
+	}
+}
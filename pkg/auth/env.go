@@ -0,0 +1,27 @@
+package auth
+
+import "os"
+
+// LoadDefaultsFromEnv fills the fields of the config that are still empty
+// from the environment:
+//
+//	ClusterName    CLUSTER_NAME, or AWS_EKS_CLUSTER_NAME
+//	Region         AWS_REGION
+//	Profile        AWS_PROFILE
+//	AssumeRoleARN  EKS_ASSUME_ROLE_ARN
+func (c *ClusterConfig) LoadDefaultsFromEnv() {
+	setFromEnv(&c.ClusterName, "CLUSTER_NAME", "AWS_EKS_CLUSTER_NAME")
+	setFromEnv(&c.Region, "AWS_REGION")
+	setFromEnv(&c.Profile, "AWS_PROFILE")
+	setFromEnv(&c.AssumeRoleARN, "EKS_ASSUME_ROLE_ARN")
+}
+
+// setFromEnv sets an empty field to the first non-empty variable of names.
+func setFromEnv(field *string, names ...string) {
+	for _, name := range names {
+		if *field != "" {
+			return
+		}
+		*field = os.Getenv(name)
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadDefaultsFromEnv(t *testing.T) {
+	setEnv(t, "CLUSTER_NAME", "")
+	setEnv(t, "AWS_EKS_CLUSTER_NAME", "fallback")
+	setEnv(t, "AWS_REGION", "eu-west-1")
+	setEnv(t, "AWS_PROFILE", "team")
+	setEnv(t, "EKS_ASSUME_ROLE_ARN", "arn:aws:iam::111122223333:role/EKSAdmin")
+
+	config := &ClusterConfig{}
+	config.LoadDefaultsFromEnv()
+	want := &ClusterConfig{
+		ClusterName:   "fallback",
+		Region:        "eu-west-1",
+		Profile:       "team",
+		AssumeRoleARN: "arn:aws:iam::111122223333:role/EKSAdmin",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadDefaultsFromEnv() set %+v, want %+v", config, want)
+	}
+
+	setEnv(t, "CLUSTER_NAME", "from-env")
+	config = &ClusterConfig{ClusterName: "explicit", Region: testRegion}
+	config.LoadDefaultsFromEnv()
+	if config.ClusterName != "explicit" || config.Region != testRegion {
+		t.Errorf("explicit fields overwritten: ClusterName %q, Region %q", config.ClusterName, config.Region)
+	}
+	if config.Profile != "team" {
+		t.Errorf("Profile = %q, want team", config.Profile)
+	}
+
+	config = &ClusterConfig{}
+	config.LoadDefaultsFromEnv()
+	if config.ClusterName != "from-env" {
+		t.Errorf("ClusterName = %q, want CLUSTER_NAME to take precedence", config.ClusterName)
+	}
+}
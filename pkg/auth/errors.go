@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors identifying why a client could not be created. Use
+// errors.Is to test for them; the underlying AWS error stays available to
+// errors.As.
+var (
+	// ErrClusterNotFound is returned when EKS has no cluster of the
+	// configured name in the region.
+	ErrClusterNotFound = errors.New("cluster not found")
+
+	// ErrSTSAccessDenied is returned when STS denies the caller identity
+	// check.
+	ErrSTSAccessDenied = errors.New("STS access denied")
+
+	// ErrTokenGeneration is returned when a token cannot be generated.
+	ErrTokenGeneration = errors.New("token generation failed")
+)
+
+// kindError attaches a sentinel error to an error without changing its
+// message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string        { return e.err.Error() }
+func (e *kindError) Unwrap() error        { return e.err }
+func (e *kindError) Cause() error         { return e.err }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+func withKind(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// awsErrorCode returns the code of an AWS error, or an empty string.
+func awsErrorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pkg/errors"
+)
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*ClusterConfig, *fakeAWS)
+		want  error
+	}{
+		{
+			name:  "cluster not found",
+			setup: func(c *ClusterConfig, _ *fakeAWS) { c.ClusterName = "missing" },
+			want:  ErrClusterNotFound,
+		},
+		{
+			name: "STS access denied",
+			setup: func(_ *ClusterConfig, fake *fakeAWS) {
+				fake.fail("GetCallerIdentity", 1, http.StatusForbidden, "AccessDenied")
+			},
+			want: ErrSTSAccessDenied,
+		},
+	}
+	kinds := []error{ErrClusterNotFound, ErrSTSAccessDenied, ErrTokenGeneration}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeAWS()
+			config := testConfig(t, fake, nil)
+			tt.setup(config, fake)
+
+			_, err := NewAuthClient(config)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("NewAuthClient() = %v, want %v", err, tt.want)
+			}
+			for _, kind := range kinds {
+				if kind != tt.want && errors.Is(err, kind) {
+					t.Errorf("error also matches %v", kind)
+				}
+			}
+			var aerr awserr.Error
+			if !errors.As(err, &aerr) {
+				t.Errorf("AWS error not available to errors.As: %v", err)
+			}
+		})
+	}
+
+	t.Run("token generation", func(t *testing.T) {
+		client := lookedUpClientConfig(t, testConfig(t, nil, nil))
+		client.TokenGenerator = &fakeTokenGenerator{err: errors.New("signing failed")}
+
+		_, err := client.NewClientSetWithEmbeddedToken()
+		if !errors.Is(err, ErrTokenGeneration) {
+			t.Fatalf("NewClientSetWithEmbeddedToken() = %v, want %v", err, ErrTokenGeneration)
+		}
+		for _, kind := range kinds[:2] {
+			if errors.Is(err, kind) {
+				t.Errorf("error also matches %v", kind)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// execAPIVersion is the exec credential API written by aws eks
+// update-kubeconfig and eksctl. kubectl 1.24 and later no longer support
+// v1alpha1.
+const execAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// WithExecCredential returns a copy of the config whose user runs command to
+// fetch a fresh token on every use, like the kubeconfigs written by eksctl.
+// command is either aws-iam-authenticator, in a release supporting the
+// v1beta1 exec API, or aws, optionally with a path. Unlike an embedded token,
+// a kubeconfig written from the result does not expire.
+func (c *ClientConfig) WithExecCredential(command string) (*ClientConfig, error) {
+	var region, roleARN, externalID string
+	if c.cluster != nil {
+		region = c.cluster.Region
+		if c.cluster.Session != nil && region == "" {
+			region = aws.StringValue(c.cluster.Session.Config.Region)
+		}
+		if len(c.cluster.assumeRoleChain()) > 1 {
+			return nil, errors.Errorf("%s cannot assume a chain of roles", command)
+		}
+		roleARN = c.cluster.assumedRole()
+		externalID = c.cluster.ExternalID
+	}
+
+	var args []string
+	switch filepath.Base(command) {
+	case "aws-iam-authenticator":
+		args = []string{"token", "-i", c.ClusterName}
+		if roleARN != "" {
+			args = append(args, "-r", roleARN)
+			if externalID != "" {
+				args = append(args, "--external-id", externalID)
+			}
+		}
+	case "aws":
+		if roleARN != "" && externalID != "" {
+			return nil, errors.New("aws eks get-token cannot pass an external ID, use aws-iam-authenticator")
+		}
+		args = []string{"eks", "get-token", "--cluster-name", c.ClusterName}
+		if region != "" {
+			args = append(args, "--region", region)
+		}
+		if roleARN != "" {
+			args = append(args, "--role-arn", roleARN)
+		}
+	default:
+		return nil, errors.Errorf("unsupported credential command %q, use aws-iam-authenticator or aws", command)
+	}
+
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion: execAPIVersion,
+		Command:    command,
+		Args:       args,
+	}
+	if region != "" {
+		exec.Env = []clientcmdapi.ExecEnvVar{{Name: "AWS_REGION", Value: region}}
+	}
+
+	// Copy the kubeconfig so the receiver keeps its own user.
+	clientConfigCopy := c.DeepCopy()
+	if clientConfigCopy.Client.AuthInfos == nil {
+		clientConfigCopy.Client.AuthInfos = make(map[string]*clientcmdapi.AuthInfo)
+	}
+	clientConfigCopy.Client.AuthInfos[c.ContextName] = &clientcmdapi.AuthInfo{Exec: exec}
+	return clientConfigCopy, nil
+}
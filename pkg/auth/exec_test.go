@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithExecCredential(t *testing.T) {
+	const roleARN = "arn:aws:iam::111122223333:role/EKSAdmin"
+	tests := []struct {
+		name       string
+		command    string
+		roleARN    string
+		externalID string
+		wantArgs   []string
+		wantErr    bool
+	}{
+		{
+			name:     "aws-iam-authenticator",
+			command:  "aws-iam-authenticator",
+			wantArgs: []string{"token", "-i", testClusterName},
+		},
+		{
+			name:       "aws-iam-authenticator with role",
+			command:    "/usr/local/bin/aws-iam-authenticator",
+			roleARN:    roleARN,
+			externalID: "ext-1234",
+			wantArgs:   []string{"token", "-i", testClusterName, "-r", roleARN, "--external-id", "ext-1234"},
+		},
+		{
+			name:     "aws",
+			command:  "aws",
+			roleARN:  roleARN,
+			wantArgs: []string{"eks", "get-token", "--cluster-name", testClusterName, "--region", testRegion, "--role-arn", roleARN},
+		},
+		{name: "aws with external ID", command: "aws", roleARN: roleARN, externalID: "ext-1234", wantErr: true},
+		{name: "unsupported", command: "kubelogin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t, nil, nil)
+			config.AssumeRoleARN = tt.roleARN
+			config.ExternalID = tt.externalID
+			config.SkipCallerIdentity = true
+			client, err := config.NewClientConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			execConfig, err := client.WithExecCredential(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("WithExecCredential() = nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithExecCredential() = %v", err)
+			}
+
+			exec := execConfig.Client.AuthInfos[client.ContextName].Exec
+			if exec == nil {
+				t.Fatal("no exec credential set")
+			}
+			if exec.Command != tt.command || !reflect.DeepEqual(exec.Args, tt.wantArgs) {
+				t.Errorf("exec = %s %v, want %s %v", exec.Command, exec.Args, tt.command, tt.wantArgs)
+			}
+			if exec.APIVersion != "client.authentication.k8s.io/v1beta1" {
+				t.Errorf("APIVersion = %q, want client.authentication.k8s.io/v1beta1", exec.APIVersion)
+			}
+			if client.Client.AuthInfos[client.ContextName].Exec != nil {
+				t.Error("exec credential set on the receiver")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+// withFIPS selects FIPS endpoints on an EKS or STS client config when
+// UseFIPSEndpoints is set.
+func (c *ClusterConfig) withFIPS(config *aws.Config) *aws.Config {
+	if c.UseFIPSEndpoints {
+		config.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+	return config
+}
+
+// checkFIPS makes sure EKS and STS have FIPS endpoints in the regions used,
+// since the SDK would otherwise guess a hostname that does not exist.
+func (c *ClusterConfig) checkFIPS() error {
+	if !c.UseFIPSEndpoints || c.AWSEndpoint != "" {
+		return nil
+	}
+
+	region := aws.StringValue(c.Session.Config.Region)
+	stsRegion := region
+	if c.STSRegion != "" {
+		stsRegion = c.STSRegion
+	}
+
+	checks := []struct{ service, region, override string }{
+		{eks.EndpointsID, region, c.EKSEndpoint},
+		{sts.EndpointsID, stsRegion, ""},
+	}
+	for _, check := range checks {
+		if check.override != "" {
+			continue
+		}
+		_, err := endpoints.DefaultResolver().EndpointFor(check.service, check.region,
+			endpoints.UseFIPSEndpointOption, endpoints.StrictMatchingOption)
+		if err != nil {
+			return errors.Errorf("no FIPS endpoint for %s in region %q", check.service, check.region)
+		}
+	}
+	return nil
+}
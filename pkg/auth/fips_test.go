@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseFIPSEndpoints(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.UseFIPSEndpoints = true
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	for _, op := range []string{"DescribeCluster", "GetCallerIdentity"} {
+		calls := fake.callsTo(op)
+		if len(calls) == 0 {
+			t.Errorf("%s not called", op)
+		}
+		for _, call := range calls {
+			if !strings.Contains(call.Host, "fips") {
+				t.Errorf("%s sent to %s, want a FIPS endpoint", op, call.Host)
+			}
+		}
+	}
+}
+
+func TestUseFIPSEndpointsUnsupportedRegion(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.UseFIPSEndpoints = true
+	config.Region = "eu-west-1"
+	config.CopySessionToRegion = true
+
+	_, err := NewAuthClient(config)
+	if err == nil || !strings.Contains(err.Error(), "no FIPS endpoint") {
+		t.Fatalf("NewAuthClient() = %v, want a missing FIPS endpoint error", err)
+	}
+	if ops := fake.operations(); len(ops) != 0 {
+		t.Errorf("AWS called without a FIPS endpoint: %q", ops)
+	}
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+)
+
+const (
+	ComponentCredentials     = "credentials"
+	ComponentSTS             = "sts"
+	ComponentDescribeCluster = "describeCluster"
+	ComponentAPIServer       = "apiserver"
+)
+
+const defaultPingTimeout = 10 * time.Second
+
+type ComponentStatus struct {
+	Name    string
+	Healthy bool
+	Skipped bool
+	Latency time.Duration
+	Error   string
+}
+
+type HealthReport struct {
+	Components []ComponentStatus
+}
+
+// Healthy reports whether every component check passed.
+func (r *HealthReport) Healthy() bool {
+	for _, c := range r.Components {
+		if !c.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// errSkipped is returned by a health check that does not apply to the config.
+var errSkipped = errors.New("check skipped")
+
+// HealthCheck checks in turn that AWS credentials resolve, the STS caller
+// identity can be retrieved, the cluster can be described and the API server
+// answers /healthz. Like NewAuthClient, the identity check is skipped with
+// SkipCallerIdentity and the cluster is not described if its endpoint and CA
+// are given. Checks after the first failure are skipped. The report is
+// always returned; the error is that of the first failed check.
+func HealthCheck(ctx context.Context, config *ClusterConfig) (*HealthReport, error) {
+	report := &HealthReport{}
+
+	var (
+		stsAPI     *sts.STS
+		iamRoleARN string
+	)
+	checks := []struct {
+		name string
+		fn   func() error
+	}{
+		{ComponentCredentials, func() error {
+			if err := config.ensureSession(); err != nil {
+				return err
+			}
+			_, err := config.Session.Config.Credentials.GetWithContext(ctx)
+			return err
+		}},
+		{ComponentSTS, func() (err error) {
+			stsAPI = config.newSTS()
+			if config.SkipCallerIdentity {
+				iamRoleARN = config.assumedRole()
+				return errSkipped
+			}
+			iamRoleARN, err = config.checkAuth(ctx, stsAPI)
+			return err
+		}},
+		{ComponentDescribeCluster, func() error {
+			if err := config.resolveClusterName(); err != nil {
+				return err
+			}
+			if err := config.loadCAFile(); err != nil {
+				return err
+			}
+			if config.lookupDisabled() {
+				return errSkipped
+			}
+			return config.loadConfig(ctx)
+		}},
+		{ComponentAPIServer, func() error {
+			// The caller identity is known from the STS check.
+			if err := config.checkSTSEndpoint(stsAPI.Endpoint); err != nil {
+				return err
+			}
+			client, err := config.newClientConfig(stsAPI, iamRoleARN)
+			if err != nil {
+				return err
+			}
+			return client.Ping(ctx)
+		}},
+	}
+
+	var firstErr error
+	for _, check := range checks {
+		status := ComponentStatus{Name: check.name}
+		if firstErr != nil {
+			status.Skipped = true
+			report.Components = append(report.Components, status)
+			continue
+		}
+
+		start := time.Now()
+		err := check.fn()
+		status.Latency = time.Since(start)
+		if err == errSkipped {
+			status.Skipped = true
+			err = nil
+		}
+		status.Healthy = err == nil
+		if err != nil {
+			status.Error = err.Error()
+			firstErr = errors.Wrapf(err, "%s check failed", check.name)
+		}
+
+		config.logger().
+			WithField("cluster", config.clusterID()).
+			WithField("component", check.name).
+			WithField("latency", status.Latency).
+			Debugf("Health check healthy=%t skipped=%t", status.Healthy, status.Skipped)
+		report.Components = append(report.Components, status)
+	}
+	return report, firstErr
+}
+
+// Ping checks that the API server is reachable and accepts a freshly
+// generated token by calling its /healthz endpoint. It gives up after 10
+// seconds unless the context has an earlier deadline.
+func (c *ClientConfig) Ping(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPingTimeout)
+		defer cancel()
+	}
+
+	cs, err := c.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		return err
+	}
+	if _, err := cs.Discovery().RESTClient().Get().AbsPath("/healthz").Context(ctx).Do().Raw(); err != nil {
+		return errors.Wrapf(err, "API server %s not reachable", c.Endpoint())
+	}
+	return nil
+}
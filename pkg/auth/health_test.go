@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+
+	report, err := HealthCheck(context.Background(), config)
+	if err != nil {
+		t.Fatalf("HealthCheck() = %v", err)
+	}
+	if !report.Healthy() {
+		t.Errorf("report = %+v, want healthy", report)
+	}
+	wantComponents := []string{ComponentCredentials, ComponentSTS, ComponentDescribeCluster, ComponentAPIServer}
+	if len(report.Components) != len(wantComponents) {
+		t.Fatalf("report has %d components, want %d", len(report.Components), len(wantComponents))
+	}
+	for i, c := range report.Components {
+		if c.Name != wantComponents[i] || c.Skipped {
+			t.Errorf("component %d = %+v, want %s checked", i, c, wantComponents[i])
+		}
+	}
+	if n := len(fake.callsTo("GetCallerIdentity")); n != 1 {
+		t.Errorf("GetCallerIdentity called %d times, want 1", n)
+	}
+}
+
+func TestHealthCheckGivenEndpoint(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	config := &ClusterConfig{
+		ClusterName:              testClusterName,
+		MasterEndpoint:           api.URL,
+		CertificateAuthorityData: api.caData(),
+		Session:                  fake.session(t),
+		Logger:                   newTestLogger(),
+	}
+
+	report, err := HealthCheck(context.Background(), config)
+	if err != nil {
+		t.Fatalf("HealthCheck() = %v", err)
+	}
+	if c := report.Components[2]; !c.Skipped || !c.Healthy {
+		t.Errorf("%s = %+v, want skipped", c.Name, c)
+	}
+	if calls := fake.callsTo("DescribeCluster"); len(calls) != 0 {
+		t.Errorf("DescribeCluster called %d times with a given endpoint and CA", len(calls))
+	}
+	if n := len(fake.callsTo("GetCallerIdentity")); n != 1 {
+		t.Errorf("GetCallerIdentity called %d times, want 1", n)
+	}
+}
+
+func TestHealthCheckSkipCallerIdentity(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.SkipCallerIdentity = true
+
+	report, err := HealthCheck(context.Background(), config)
+	if err != nil {
+		t.Fatalf("HealthCheck() = %v", err)
+	}
+	if c := report.Components[1]; !c.Skipped || !c.Healthy {
+		t.Errorf("%s = %+v, want skipped", c.Name, c)
+	}
+	if calls := fake.callsTo("GetCallerIdentity"); len(calls) != 0 {
+		t.Errorf("GetCallerIdentity called %d times with SkipCallerIdentity", len(calls))
+	}
+}
+
+func TestHealthCheckFailure(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	fake.fail("GetCallerIdentity", 1, http.StatusForbidden, "AccessDenied")
+
+	report, err := HealthCheck(context.Background(), config)
+	if err == nil {
+		t.Fatal("HealthCheck() = nil, want the STS error")
+	}
+	if report.Healthy() {
+		t.Error("report is healthy despite the failed STS check")
+	}
+	if c := report.Components[1]; c.Healthy || c.Error == "" {
+		t.Errorf("%s = %+v, want failed", c.Name, c)
+	}
+	for _, c := range report.Components[2:] {
+		if !c.Skipped {
+			t.Errorf("%s = %+v, want skipped after the failure", c.Name, c)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	api := newFakeAPIServer(t)
+	client := lookedUpClientConfig(t, testConfig(t, nil, api))
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() = %v", err)
+	}
+	if tokens := api.requestTokens(); len(tokens) != 1 || tokens[0] != "k8s-aws-v1."+testClusterName+"-1" {
+		t.Errorf("API server got tokens %q, want the generated one", tokens)
+	}
+
+	api.reject = func(string) bool { return true }
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("Ping() = nil for a rejected token")
+	}
+}
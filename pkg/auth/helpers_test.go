@@ -0,0 +1,681 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
+)
+
+const (
+	testRegion      = "us-west-2"
+	testAccount     = "111122223333"
+	testClusterName = "test"
+	testAccessKeyID = "AKIDBASE"
+	testCallerARN   = "arn:aws:iam::111122223333:user/tester"
+)
+
+// awsCall is a request received by fakeAWS.
+type awsCall struct {
+	Service     string
+	Operation   string
+	AccessKeyID string
+	Host        string
+	UserAgent   string
+	Params      url.Values
+}
+
+type awsFailure struct {
+	status int
+	code   string
+}
+
+// fakeAWS is an http.RoundTripper serving the STS and EKS APIs used by this
+// package from memory. Roles assumed through it get credentials with their
+// own access key, so that tests can tell which identity signed a request.
+type fakeAWS struct {
+	mu       sync.Mutex
+	calls    []awsCall
+	clusters map[string]*eks.Cluster
+	failures map[string][]awsFailure
+	roles    map[string]string // access key ID to role ARN
+
+	// pageSize limits the clusters returned per ListClusters page.
+	pageSize int
+	// delay holds every response back, or until the request is canceled.
+	delay time.Duration
+}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{
+		clusters: map[string]*eks.Cluster{},
+		failures: map[string][]awsFailure{},
+		roles:    map[string]string{},
+	}
+}
+
+// addCluster makes DescribeCluster return an active cluster.
+func (f *fakeAWS) addCluster(name, endpoint, ca string) *eks.Cluster {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := testCluster(name, endpoint, ca)
+	f.clusters[name] = c
+	return c
+}
+
+// fail makes the next n calls of op fail with the given status and code.
+func (f *fakeAWS) fail(op string, n, status int, code string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < n; i++ {
+		f.failures[op] = append(f.failures[op], awsFailure{status, code})
+	}
+}
+
+// callsTo returns the calls made to op, or all calls if op is empty.
+func (f *fakeAWS) callsTo(op string) []awsCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []awsCall
+	for _, c := range f.calls {
+		if op == "" || c.Operation == op {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// operations returns the operations called, in order.
+func (f *fakeAWS) operations() []string {
+	var ops []string
+	for _, c := range f.callsTo("") {
+		ops = append(ops, c.Operation)
+	}
+	return ops
+}
+
+// accessKeyFor returns the access key ID of the credentials issued for
+// roleARN, or an empty string if it was not assumed.
+func (f *fakeAWS) accessKeyFor(roleARN string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, role := range f.roles {
+		if role == roleARN {
+			return key
+		}
+	}
+	return ""
+}
+
+func (f *fakeAWS) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.delay > 0 {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(f.delay):
+		}
+	}
+
+	call := awsCall{Host: req.URL.Host, UserAgent: req.Header.Get("User-Agent"), AccessKeyID: signingKey(req)}
+	call.Service = signingService(req)
+
+	rec := httptest.NewRecorder()
+	switch call.Service {
+	case "sts":
+		f.serveSTS(rec, req, &call)
+	case "eks":
+		f.serveEKS(rec, req, &call)
+	default:
+		http.Error(rec, "unknown service", http.StatusBadRequest)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// signingKey returns the access key ID in the SigV4 Authorization header.
+func signingKey(req *http.Request) string {
+	scope := credentialScope(req)
+	if len(scope) == 0 {
+		return ""
+	}
+	return scope[0]
+}
+
+// signingService returns the service a request was signed for. Unsigned
+// requests, such as AssumeRoleWithWebIdentity, are told apart by host.
+func signingService(req *http.Request) string {
+	if scope := credentialScope(req); len(scope) > 3 {
+		return scope[3]
+	}
+	if strings.HasPrefix(req.URL.Host, "sts") {
+		return "sts"
+	}
+	return ""
+}
+
+func credentialScope(req *http.Request) []string {
+	auth := req.Header.Get("Authorization")
+	i := strings.Index(auth, "Credential=")
+	if i < 0 {
+		return nil
+	}
+	cred := strings.SplitN(auth[i+len("Credential="):], ",", 2)[0]
+	return strings.Split(cred, "/")
+}
+
+// nextFailure pops a failure scripted for op.
+func (f *fakeAWS) nextFailure(op string) (awsFailure, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failures := f.failures[op]
+	if len(failures) == 0 {
+		return awsFailure{}, false
+	}
+	f.failures[op] = failures[1:]
+	return failures[0], true
+}
+
+func (f *fakeAWS) serveSTS(w http.ResponseWriter, req *http.Request, call *awsCall) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	call.Params = req.Form
+	call.Operation = req.Form.Get("Action")
+
+	if failure, ok := f.nextFailure(call.Operation); ok {
+		w.WriteHeader(failure.status)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>scripted failure</Message></Error><RequestId>test</RequestId></ErrorResponse>`, failure.code)
+		return
+	}
+
+	switch call.Operation {
+	case "GetCallerIdentity":
+		fmt.Fprintf(w, `<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>%s</Arn><UserId>AIDTEST</UserId><Account>%s</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`,
+			f.callerARN(call.AccessKeyID), testAccount)
+	case "AssumeRole", "AssumeRoleWithWebIdentity":
+		roleARN := req.Form.Get("RoleArn")
+		f.mu.Lock()
+		key := "ASIA" + strconv.Itoa(len(f.roles)+1)
+		f.roles[key] = roleARN
+		f.mu.Unlock()
+		fmt.Fprintf(w, `<%[1]sResponse><%[1]sResult><Credentials><AccessKeyId>%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken><Expiration>%s</Expiration></Credentials><AssumedRoleUser><Arn>%s</Arn><AssumedRoleId>AROATEST:eksutil</AssumedRoleId></AssumedRoleUser></%[1]sResult></%[1]sResponse>`,
+			call.Operation, key, time.Now().Add(time.Hour).UTC().Format(time.RFC3339), assumedRoleARN(roleARN))
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+	}
+}
+
+// callerARN returns the identity of the credentials with the access key.
+func (f *fakeAWS) callerARN(accessKeyID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if roleARN, ok := f.roles[accessKeyID]; ok {
+		return assumedRoleARN(roleARN)
+	}
+	return testCallerARN
+}
+
+func assumedRoleARN(roleARN string) string {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return roleARN
+	}
+	parts := strings.Split(parsed.Resource, "/")
+	parsed.Service = "sts"
+	parsed.Resource = "assumed-role/" + parts[len(parts)-1] + "/eksutil"
+	return parsed.String()
+}
+
+func (f *fakeAWS) serveEKS(w http.ResponseWriter, req *http.Request, call *awsCall) {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "clusters":
+		call.Operation = "ListClusters"
+	case len(parts) == 2 && parts[0] == "clusters":
+		call.Operation = "DescribeCluster"
+	default:
+		http.Error(w, "unknown operation", http.StatusBadRequest)
+		return
+	}
+	call.Params = req.URL.Query()
+
+	if failure, ok := f.nextFailure(call.Operation); ok {
+		writeEKSError(w, failure.status, failure.code)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch call.Operation {
+	case "ListClusters":
+		names := make([]string, 0, len(f.clusters))
+		for name := range f.clusters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		start, _ := strconv.Atoi(call.Params.Get("nextToken"))
+		end := len(names)
+		if f.pageSize > 0 && start+f.pageSize < end {
+			end = start + f.pageSize
+		}
+		out := &eks.ListClustersOutput{Clusters: aws.StringSlice(names[start:end])}
+		if end < len(names) {
+			out.NextToken = aws.String(strconv.Itoa(end))
+		}
+		writeEKSOutput(w, out)
+	case "DescribeCluster":
+		cluster, ok := f.clusters[parts[1]]
+		if !ok {
+			writeEKSError(w, http.StatusNotFound, eks.ErrCodeResourceNotFoundException)
+			return
+		}
+		writeEKSOutput(w, &eks.DescribeClusterOutput{Cluster: cluster})
+	}
+}
+
+func writeEKSOutput(w http.ResponseWriter, out interface{}) {
+	b, err := jsonutil.BuildJSON(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func writeEKSError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-Errortype", code)
+	w.WriteHeader(status)
+	fmt.Fprint(w, `{"message":"scripted failure"}`)
+}
+
+// session returns a session with static credentials whose requests are
+// served by f.
+func (f *fakeAWS) session(t *testing.T) *session.Session {
+	t.Helper()
+	isolateAWSEnv(t)
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			Region:      aws.String(testRegion),
+			Credentials: credentials.NewStaticCredentials(testAccessKeyID, "secret", ""),
+			HTTPClient:  &http.Client{Transport: f},
+			MaxRetries:  aws.Int(0),
+		},
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+// installDefault serves the requests of sessions created by this package
+// from f, with static credentials from the environment and no shared config.
+func (f *fakeAWS) installDefault(t *testing.T) {
+	t.Helper()
+	isolateAWSEnv(t)
+	setEnv(t, "AWS_ACCESS_KEY_ID", testAccessKeyID)
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "secret")
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = f
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+}
+
+// isolateAWSEnv hides the AWS configuration of the machine running the tests.
+func isolateAWSEnv(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	setEnv(t, "AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	setEnv(t, "AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	setEnv(t, "AWS_EC2_METADATA_DISABLED", "true")
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_REGION", "AWS_DEFAULT_REGION",
+		"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_SDK_LOAD_CONFIG",
+		"AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_SESSION_NAME",
+		"AWS_STS_REGIONAL_ENDPOINTS", "AWS_USE_FIPS_ENDPOINT", "AWS_CA_BUNDLE",
+	} {
+		setEnv(t, name, "")
+	}
+}
+
+// setEnv sets an environment variable for the duration of the test.
+func setEnv(t *testing.T, name, value string) {
+	t.Helper()
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+// testCluster returns an active cluster as described by EKS.
+func testCluster(name, endpoint, ca string) *eks.Cluster {
+	return &eks.Cluster{
+		Name:                 aws.String(name),
+		Arn:                  aws.String("arn:aws:eks:" + testRegion + ":" + testAccount + ":cluster/" + name),
+		Endpoint:             aws.String(endpoint),
+		CertificateAuthority: &eks.Certificate{Data: aws.String(ca)},
+		Status:               aws.String(eks.ClusterStatusActive),
+		Version:              aws.String("1.17"),
+		ResourcesVpcConfig: &eks.VpcConfigResponse{
+			EndpointPublicAccess:  aws.Bool(true),
+			EndpointPrivateAccess: aws.Bool(false),
+		},
+	}
+}
+
+// fakeEKS is an EKS client answering DescribeCluster with clusters. Other
+// calls panic.
+type fakeEKS struct {
+	eksiface.EKSAPI
+
+	mu       sync.Mutex
+	clusters map[string]*eks.Cluster
+	// errs are returned by the next DescribeCluster calls.
+	errs          []error
+	describeCalls int
+}
+
+func newFakeEKS(clusters ...*eks.Cluster) *fakeEKS {
+	f := &fakeEKS{clusters: map[string]*eks.Cluster{}}
+	for _, c := range clusters {
+		f.clusters[aws.StringValue(c.Name)] = c
+	}
+	return f
+}
+
+func (f *fakeEKS) DescribeClusterWithContext(ctx aws.Context, input *eks.DescribeClusterInput, opts ...request.Option) (*eks.DescribeClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.describeCalls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	cluster, ok := f.clusters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awsRequestFailure(http.StatusNotFound, eks.ErrCodeResourceNotFoundException)
+	}
+	return &eks.DescribeClusterOutput{Cluster: cluster}, nil
+}
+
+func (f *fakeEKS) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.describeCalls
+}
+
+func awsRequestFailure(status int, code string) error {
+	return awserr.NewRequestFailure(awserr.New(code, "scripted failure", nil), status, "test")
+}
+
+// fakeTokenGenerator returns numbered tokens valid for 15 minutes.
+type fakeTokenGenerator struct {
+	mu  sync.Mutex
+	n   int
+	err error
+}
+
+func (g *fakeTokenGenerator) GetWithSTS(clusterID string, stsAPI stsiface.STSAPI) (token.Token, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return token.Token{}, g.err
+	}
+	g.n++
+	return token.Token{
+		Token:      fmt.Sprintf("k8s-aws-v1.%s-%d", clusterID, g.n),
+		Expiration: time.Now().Add(15 * time.Minute),
+	}, nil
+}
+
+func (g *fakeTokenGenerator) generated() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+// logEntry is a message logged through a testLogger.
+type logEntry struct {
+	Level   string
+	Message string
+	Fields  map[string]interface{}
+}
+
+// testLogger is a Logger recording what is logged.
+type testLogger struct {
+	log    *logRecord
+	fields map[string]interface{}
+}
+
+type logRecord struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func newTestLogger() *testLogger {
+	return &testLogger{log: &logRecord{}}
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) { l.add("debug", format, args) }
+func (l *testLogger) Infof(format string, args ...interface{})  { l.add("info", format, args) }
+func (l *testLogger) Warnf(format string, args ...interface{})  { l.add("warn", format, args) }
+func (l *testLogger) Errorf(format string, args ...interface{}) { l.add("error", format, args) }
+
+func (l *testLogger) WithField(key string, value interface{}) Logger {
+	fields := map[string]interface{}{key: value}
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	return &testLogger{log: l.log, fields: fields}
+}
+
+func (l *testLogger) add(level, format string, args []interface{}) {
+	l.log.mu.Lock()
+	defer l.log.mu.Unlock()
+	l.log.entries = append(l.log.entries, logEntry{level, fmt.Sprintf(format, args...), l.fields})
+}
+
+// find returns the first entry of level whose message contains msg.
+func (l *testLogger) find(level, msg string) (logEntry, bool) {
+	l.log.mu.Lock()
+	defer l.log.mu.Unlock()
+	for _, e := range l.log.entries {
+		if e.Level == level && strings.Contains(e.Message, msg) {
+			return e, true
+		}
+	}
+	return logEntry{}, false
+}
+
+// fakeAPIServer is a Kubernetes API server answering the discovery and
+// health requests made by this package. It records the bearer tokens of
+// requests and rejects those for which reject returns true. POST requests
+// are answered with 201 Created and their bodies recorded.
+type fakeAPIServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	tokens []string
+	posted []string
+	reject func(token string) bool
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	s := &fakeAPIServer{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeAPIServer) serve(w http.ResponseWriter, req *http.Request) {
+	tok := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	s.tokens = append(s.tokens, tok)
+	reject := s.reject
+	s.mu.Unlock()
+	if reject != nil && reject(tok) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Method == http.MethodPost {
+		data, _ := ioutil.ReadAll(req.Body)
+		s.mu.Lock()
+		s.posted = append(s.posted, string(data))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	var body interface{}
+	switch req.URL.Path {
+	case "/healthz":
+		fmt.Fprint(w, "ok")
+		return
+	case "/version":
+		body = map[string]string{"major": "1", "minor": "17", "gitVersion": "v1.17.17-eks"}
+	case "/api":
+		body = map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}}
+	case "/apis":
+		body = map[string]interface{}{"kind": "APIGroupList", "groups": []interface{}{map[string]interface{}{
+			"name":             "apps",
+			"versions":         []interface{}{map[string]string{"groupVersion": "apps/v1", "version": "v1"}},
+			"preferredVersion": map[string]string{"groupVersion": "apps/v1", "version": "v1"},
+		}}}
+	case "/api/v1":
+		body = apiResources("v1", "pods", "Pod", true)
+	case "/apis/apps/v1":
+		body = apiResources("apps/v1", "deployments", "Deployment", true)
+	case "/api/v1/namespaces":
+		body = map[string]interface{}{"kind": "NamespaceList", "apiVersion": "v1", "items": []interface{}{
+			map[string]interface{}{"metadata": map[string]string{"name": "default"}},
+		}}
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func apiResources(groupVersion, name, kind string, namespaced bool) map[string]interface{} {
+	return map[string]interface{}{
+		"kind":         "APIResourceList",
+		"groupVersion": groupVersion,
+		"resources": []interface{}{map[string]interface{}{
+			"name": name, "kind": kind, "namespaced": namespaced,
+			"verbs": []string{"get", "list"},
+		}},
+	}
+}
+
+// requestTokens returns the bearer tokens of the requests received.
+func (s *fakeAPIServer) requestTokens() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.tokens...)
+}
+
+// postedBodies returns the bodies of the POST requests received.
+func (s *fakeAPIServer) postedBodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.posted...)
+}
+
+// caData returns the server certificate as EKS encodes the cluster CA.
+func (s *fakeAPIServer) caData() string {
+	return base64.StdEncoding.EncodeToString(s.caPEM())
+}
+
+func (s *fakeAPIServer) caPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+}
+
+// testConfig returns a config for a cluster served by api, looked up through
+// fake. Either may be nil when not needed. Log output goes to a testLogger.
+func testConfig(t *testing.T, fake *fakeAWS, api *fakeAPIServer) *ClusterConfig {
+	t.Helper()
+	if fake == nil {
+		fake = newFakeAWS()
+	}
+	if api == nil {
+		api = newFakeAPIServer(t)
+	}
+	fake.addCluster(testClusterName, api.URL, api.caData())
+	return &ClusterConfig{
+		ClusterName: testClusterName,
+		Region:      testRegion,
+		Session:     fake.session(t),
+		Logger:      newTestLogger(),
+	}
+}
+
+// resetClusterCache empties the cluster cache before and after the test.
+func resetClusterCache(t *testing.T) {
+	reset := func() {
+		clusterCache.Lock()
+		clusterCache.entries = make(map[string]clusterCacheEntry)
+		clusterCache.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// withTimeout returns a context canceled at the end of the test or after d.
+func withTimeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
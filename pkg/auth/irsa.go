@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultWebIdentityRetries    = 3
+	defaultWebIdentityRetryDelay = 500 * time.Millisecond
+)
+
+// webIdentityCredentials returns credentials for the role and token file of
+// the IRSA environment variables.
+func webIdentityCredentials(sess *session.Session) (*credentials.Credentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN == "" || tokenFile == "" {
+		return nil, errors.New("UseWebIdentity requires AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE to be set")
+	}
+	return stscreds.NewWebIdentityCredentials(sess, roleARN, os.Getenv("AWS_ROLE_SESSION_NAME"), tokenFile), nil
+}
+
+// retryWebIdentity calls fn, retrying while it fails because the web identity
+// token file could not be read. It is safe to call on a nil config.
+func (c *ClusterConfig) retryWebIdentity(fn func() error) error {
+	retries, delay := defaultWebIdentityRetries, defaultWebIdentityRetryDelay
+	if c != nil {
+		if c.WebIdentityRetries != 0 {
+			retries = c.WebIdentityRetries
+		}
+		if c.WebIdentityRetryDelay > 0 {
+			delay = c.WebIdentityRetryDelay
+		}
+	}
+
+	err := fn()
+	for i := 0; i < retries && err != nil && isWebIdentityFileError(err); i++ {
+		c.logger().WithField("attempt", i+1).Warnf("Web identity token file unavailable, retrying in %s", delay)
+		time.Sleep(delay)
+		err = fn()
+	}
+	return err
+}
+
+// isWebIdentityFileError reports whether err was caused by the web identity
+// token file being missing or unreadable.
+func isWebIdentityFileError(err error) bool {
+	for err != nil {
+		if os.IsNotExist(err) {
+			return true
+		}
+
+		switch e := err.(type) {
+		case awserr.BatchedErrors:
+			for _, orig := range e.OrigErrs() {
+				if isWebIdentityFileError(orig) {
+					return true
+				}
+			}
+			return false
+		case awserr.Error:
+			if e.Code() == stscreds.ErrCodeWebIdentity && strings.Contains(e.Message(), "unable to read file") {
+				return true
+			}
+			err = e.OrigErr()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
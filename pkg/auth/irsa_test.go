@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPodRoleARN = "arn:aws:iam::111122223333:role/pod-role"
+
+// newWebIdentityTestConfig returns a config in an IRSA environment.
+func newWebIdentityTestConfig(t *testing.T, fake *fakeAWS) *ClusterConfig {
+	t.Helper()
+	config := newTestSessionConfig(t, fake)
+	setEnv(t, "AWS_ROLE_ARN", testPodRoleARN)
+	setEnv(t, "AWS_WEB_IDENTITY_TOKEN_FILE", writeTestFile(t, "token", []byte("oidc-token")))
+	return config
+}
+
+func TestUseWebIdentity(t *testing.T) {
+	fake := newFakeAWS()
+	config := newWebIdentityTestConfig(t, fake)
+	config.UseWebIdentity = true
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	calls := fake.callsTo("AssumeRoleWithWebIdentity")
+	if len(calls) != 1 {
+		t.Fatalf("AssumeRoleWithWebIdentity called %d times, want 1", len(calls))
+	}
+	if calls[0].Params.Get("RoleArn") != testPodRoleARN || calls[0].Params.Get("WebIdentityToken") != "oidc-token" {
+		t.Errorf("AssumeRoleWithWebIdentity params = %v", calls[0].Params)
+	}
+	key := fake.accessKeyFor(testPodRoleARN)
+	for _, op := range []string{"DescribeCluster", "GetCallerIdentity"} {
+		for _, call := range fake.callsTo(op) {
+			if call.AccessKeyID != key {
+				t.Errorf("%s signed by %s, want the web identity key %s", op, call.AccessKeyID, key)
+			}
+		}
+	}
+}
+
+func TestWebIdentityNotUsedByDefault(t *testing.T) {
+	fake := newFakeAWS()
+	config := newWebIdentityTestConfig(t, fake)
+
+	// The static environment credentials take precedence in the default chain.
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+	if calls := fake.callsTo("AssumeRoleWithWebIdentity"); len(calls) != 0 {
+		t.Errorf("web identity used %d times without UseWebIdentity", len(calls))
+	}
+}
+
+func TestUseWebIdentityWithoutEnv(t *testing.T) {
+	config := newTestSessionConfig(t, newFakeAWS())
+	config.UseWebIdentity = true
+
+	if _, err := NewAuthClient(config); err == nil {
+		t.Error("NewAuthClient() = nil error without AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+}
+
+// webIdentityAttempts returns a function getting web identity credentials for
+// tokenFile, and a count of its calls.
+func webIdentityAttempts(t *testing.T, fake *fakeAWS, tokenFile string) (func() error, *int) {
+	t.Helper()
+	sess := fake.session(t)
+	setEnv(t, "AWS_ROLE_ARN", testPodRoleARN)
+	setEnv(t, "AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	creds, err := webIdentityCredentials(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attempts int
+	return func() error {
+		attempts++
+		_, err := creds.Get()
+		return err
+	}, &attempts
+}
+
+func TestRetryWebIdentityTokenFileAppears(t *testing.T) {
+	fake := newFakeAWS()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	get, attempts := webIdentityAttempts(t, fake, tokenFile)
+	logger := newTestLogger()
+	config := &ClusterConfig{Logger: logger, WebIdentityRetryDelay: time.Millisecond}
+
+	// The token is projected into the pod after the first attempt.
+	err := config.retryWebIdentity(func() error {
+		err := get()
+		if *attempts == 1 {
+			if werr := ioutil.WriteFile(tokenFile, []byte("oidc-token"), 0600); werr != nil {
+				t.Fatal(werr)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("retryWebIdentity() = %v", err)
+	}
+	if *attempts != 2 {
+		t.Errorf("%d attempts, want 2", *attempts)
+	}
+	if n := len(fake.callsTo("AssumeRoleWithWebIdentity")); n != 1 {
+		t.Errorf("AssumeRoleWithWebIdentity called %d times, want once the token file exists", n)
+	}
+	if _, ok := logger.find("warn", "Web identity token file unavailable"); !ok {
+		t.Error("retry not logged")
+	}
+}
+
+func TestRetryWebIdentityGivesUp(t *testing.T) {
+	fake := newFakeAWS()
+	get, attempts := webIdentityAttempts(t, fake, filepath.Join(t.TempDir(), "missing"))
+	config := &ClusterConfig{
+		Logger:                newTestLogger(),
+		WebIdentityRetries:    2,
+		WebIdentityRetryDelay: 20 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := config.retryWebIdentity(get)
+	elapsed := time.Since(start)
+
+	if err == nil || !isWebIdentityFileError(err) {
+		t.Fatalf("retryWebIdentity() = %v, want the token file error", err)
+	}
+	if *attempts != 3 {
+		t.Errorf("%d attempts, want the first and 2 retries", *attempts)
+	}
+	if elapsed < 40*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("gave up after %s, want after the 2 retry delays", elapsed)
+	}
+	if n := len(fake.callsTo("AssumeRoleWithWebIdentity")); n != 0 {
+		t.Errorf("AssumeRoleWithWebIdentity called %d times without a token file", n)
+	}
+}
+
+func TestRetryWebIdentityOtherError(t *testing.T) {
+	fake := newFakeAWS()
+	fake.fail("AssumeRoleWithWebIdentity", 1, 403, "AccessDenied")
+	get, attempts := webIdentityAttempts(t, fake, writeTestFile(t, "token", []byte("oidc-token")))
+	config := &ClusterConfig{Logger: newTestLogger(), WebIdentityRetryDelay: time.Millisecond}
+
+	if err := config.retryWebIdentity(get); err == nil {
+		t.Fatal("retryWebIdentity() = nil, want the AccessDenied error")
+	}
+	if *attempts != 1 {
+		t.Errorf("%d attempts, want errors other than the token file not retried", *attempts)
+	}
+}
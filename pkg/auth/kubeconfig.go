@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// GetCurrentContext returns the current context of the kubeconfig at path.
+func GetCurrentContext(path string) (string, error) {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "loading kubeconfig %q", path)
+	}
+	return config.CurrentContext, nil
+}
+
+// SetCurrentContext changes the current context of the kubeconfig at path.
+// The context must already exist. The file is replaced atomically.
+func SetCurrentContext(path, contextName string) error {
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "loading kubeconfig %q", path)
+	}
+
+	if _, ok := config.Contexts[contextName]; !ok {
+		return errors.Errorf("context %q not found in kubeconfig %q", contextName, path)
+	}
+	config.CurrentContext = contextName
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return errors.Wrap(err, "serializing kubeconfig")
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// NewAuthClientFromKubeconfig creates a clientset from a context of an
+// existing kubeconfig, without calling AWS, e.g. for local development. The
+// current context is used when contextName is empty, and the default
+// kubeconfig locations when path is empty.
+func NewAuthClientFromKubeconfig(path, contextName string) (clientset.Interface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		rules.ExplicitPath = path
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "loading kubeconfig")
+	}
+
+	client, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Kubernetes client")
+	}
+	return client, nil
+}
+
+// WriteKubeconfig writes the client config to a kubeconfig file at path, for
+// use by kubectl and other tools. Unless the config already carries an exec
+// credential, a token is generated and embedded. Parent directories are
+// created as needed and the file is written with mode 0600.
+func (c *ClientConfig) WriteKubeconfig(path string) error {
+	config := c
+	if authInfo := c.Client.AuthInfos[c.ContextName]; authInfo == nil || authInfo.Exec == nil {
+		var err error
+		if config, err = c.WithEmbeddedToken(); err != nil {
+			return err
+		}
+		if err := config.checkEmbeddedToken(); err != nil {
+			return err
+		}
+	}
+
+	data, err := clientcmd.Write(*config.Client)
+	if err != nil {
+		return errors.Wrap(err, "serializing kubeconfig")
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Errorf("%s: making directory for file: %v", path, err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return errors.Errorf("%s: creating temporary file: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return errors.Errorf("%s: changing file mode: %v", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Errorf("%s: writing file: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Errorf("%s: writing file: %v", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Errorf("%s: replacing file: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestWriteKubeconfig(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	path := filepath.Join(t.TempDir(), "kube", "config")
+	if err := client.WriteKubeconfig(path); err != nil {
+		t.Fatalf("WriteKubeconfig() = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("kubeconfig mode = %o, want 600", mode)
+	}
+
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() = %v", err)
+	}
+	if loaded.CurrentContext != client.ContextName {
+		t.Errorf("CurrentContext = %q, want %q", loaded.CurrentContext, client.ContextName)
+	}
+	cluster := loaded.Clusters[loaded.Contexts[client.ContextName].Cluster]
+	want := client.currentCluster()
+	if cluster.Server != want.Server || !bytes.Equal(cluster.CertificateAuthorityData, want.CertificateAuthorityData) {
+		t.Errorf("cluster = %+v, want %+v", cluster, want)
+	}
+	if tok := loaded.AuthInfos[client.ContextName].Token; tok != "k8s-aws-v1."+testClusterName+"-1" {
+		t.Errorf("token = %q, want the generated one", tok)
+	}
+}
+
+func TestNewAuthClientFromKubeconfig(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := clientcmdapi.NewConfig()
+	config.Clusters["local"] = &clientcmdapi.Cluster{Server: api.URL, CertificateAuthorityData: api.caPEM()}
+	config.AuthInfos["developer"] = &clientcmdapi.AuthInfo{Token: "local-token"}
+	config.Contexts["other"] = &clientcmdapi.Context{Cluster: "missing", AuthInfo: "developer"}
+	config.Contexts["local"] = &clientcmdapi.Context{Cluster: "local", AuthInfo: "developer"}
+	config.CurrentContext = "other"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := NewAuthClientFromKubeconfig(path, "local")
+	if err != nil {
+		t.Fatalf("NewAuthClientFromKubeconfig() = %v", err)
+	}
+	version, err := cs.Discovery().ServerVersion()
+	if err != nil {
+		t.Fatalf("ServerVersion() = %v", err)
+	}
+	if version.GitVersion != "v1.17.17-eks" {
+		t.Errorf("GitVersion = %q, want v1.17.17-eks", version.GitVersion)
+	}
+	if tokens := api.requestTokens(); len(tokens) != 1 || tokens[0] != "local-token" {
+		t.Errorf("API server got tokens %q, want the kubeconfig token", tokens)
+	}
+}
+
+func TestSetCurrentContext(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://cluster.example.com"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["first"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	config.Contexts["second"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	config.CurrentContext = "first"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := GetCurrentContext(path); err != nil || got != "first" {
+		t.Fatalf("GetCurrentContext() = %q, %v, want first", got, err)
+	}
+	if err := SetCurrentContext(path, "second"); err != nil {
+		t.Fatalf("SetCurrentContext(second) = %v", err)
+	}
+	if got, err := GetCurrentContext(path); err != nil || got != "second" {
+		t.Errorf("GetCurrentContext() = %q, %v, want second", got, err)
+	}
+
+	err := SetCurrentContext(path, "missing")
+	if err == nil || !strings.Contains(err.Error(), `context "missing" not found`) {
+		t.Errorf("SetCurrentContext(missing) = %v, want a context not found error", err)
+	}
+	if got, err := GetCurrentContext(path); err != nil || got != "second" {
+		t.Errorf("GetCurrentContext() after failed set = %q, %v, want second", got, err)
+	}
+
+	loaded, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Contexts) != 2 || loaded.AuthInfos["user"].Token != "token" {
+		t.Errorf("SetCurrentContext() changed the rest of the kubeconfig: %+v", loaded)
+	}
+}
+
+func TestGetCurrentContextMissingFile(t *testing.T) {
+	if _, err := GetCurrentContext(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("GetCurrentContext() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Logger is the logging interface used by this package. Set
+// ClusterConfig.Logger to route log output to another logging library.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	WithField(key string, value interface{}) Logger
+}
+
+// NewLogrusLogger adapts a logrus entry to Logger.
+func NewLogrusLogger(entry *log.Entry) Logger {
+	return logrusLogger{entry}
+}
+
+type logrusLogger struct {
+	entry *log.Entry
+}
+
+func (l logrusLogger) Debugf(format string, args ...interface{}) { l.entry.Debugf(format, args...) }
+func (l logrusLogger) Infof(format string, args ...interface{})  { l.entry.Infof(format, args...) }
+func (l logrusLogger) Warnf(format string, args ...interface{})  { l.entry.Warnf(format, args...) }
+func (l logrusLogger) Errorf(format string, args ...interface{}) { l.entry.Errorf(format, args...) }
+
+func (l logrusLogger) WithField(key string, value interface{}) Logger {
+	return logrusLogger{l.entry.WithField(key, value)}
+}
+
+// Log formats for ClusterConfig.LogFormat.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Loggers owned by this package so that LogFormat does not change the
+// formatter of the standard logger. Their level and output follow the
+// standard logger.
+var formatLoggers = map[string]*log.Logger{
+	LogFormatText: newFormatLogger(&log.TextFormatter{}),
+	LogFormatJSON: newFormatLogger(&log.JSONFormatter{}),
+}
+
+func newFormatLogger(formatter log.Formatter) *log.Logger {
+	l := log.New()
+	l.Formatter = formatter
+	return l
+}
+
+// logger returns the configured logger, defaulting to the standard logrus
+// logger, or a logger for LogFormat if set. It is safe to call on a nil
+// config.
+func (c *ClusterConfig) logger() Logger {
+	if c != nil && c.Logger != nil {
+		return c.Logger
+	}
+	if c != nil && c.LogFormat != "" {
+		if l, ok := formatLoggers[c.LogFormat]; ok {
+			std := log.StandardLogger()
+			l.SetLevel(std.GetLevel())
+			l.SetOutput(std.Out)
+			return NewLogrusLogger(log.NewEntry(l))
+		}
+	}
+	return NewLogrusLogger(log.NewEntry(log.StandardLogger()))
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestLoggerInjected(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	logger := newTestLogger()
+	config.Logger = logger
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := logger.find("info", "Looking up EKS cluster")
+	if !ok {
+		t.Fatal(`"Looking up EKS cluster" not logged through the injected logger`)
+	}
+	if entry.Fields["cluster"] != testClusterName {
+		t.Errorf("cluster field = %v, want %q", entry.Fields["cluster"], testClusterName)
+	}
+}
+
+func TestLogFormatJSON(t *testing.T) {
+	var out bytes.Buffer
+	std := log.StandardLogger()
+	output, formatter := std.Out, std.Formatter
+	std.SetOutput(&out)
+	t.Cleanup(func() { std.SetOutput(output) })
+
+	config := testConfig(t, nil, nil)
+	config.Logger = nil
+	config.LogFormat = LogFormatJSON
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if entry["msg"] == "Looking up EKS cluster" {
+			found = true
+			if entry["cluster"] != testClusterName {
+				t.Errorf("cluster key = %v, want %q", entry["cluster"], testClusterName)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("cluster lookup not logged, got %q", out.String())
+	}
+	if std.Formatter != formatter {
+		t.Error("formatter of the standard logger changed")
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import "time"
+
+// Metrics receives the latency and outcome of the AWS calls and token
+// generation made while creating clients, e.g. to export them to Prometheus.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	ObserveDescribeCluster(d time.Duration, err error)
+	ObserveGetCallerIdentity(d time.Duration, err error)
+	ObserveTokenGeneration(d time.Duration, err error)
+}
+
+// NoopMetrics is a Metrics that discards all observations.
+type NoopMetrics struct{}
+
+func (NoopMetrics) ObserveDescribeCluster(time.Duration, error)   {}
+func (NoopMetrics) ObserveGetCallerIdentity(time.Duration, error) {}
+func (NoopMetrics) ObserveTokenGeneration(time.Duration, error)   {}
+
+// NewLoggerMetrics returns a Metrics that logs each observation at debug
+// level, and serves as an example for adapters to metrics libraries.
+func NewLoggerMetrics(logger Logger) Metrics {
+	return loggerMetrics{logger: logger}
+}
+
+type loggerMetrics struct {
+	logger Logger
+}
+
+func (m loggerMetrics) ObserveDescribeCluster(d time.Duration, err error) {
+	m.observe("DescribeCluster", d, err)
+}
+
+func (m loggerMetrics) ObserveGetCallerIdentity(d time.Duration, err error) {
+	m.observe("GetCallerIdentity", d, err)
+}
+
+func (m loggerMetrics) ObserveTokenGeneration(d time.Duration, err error) {
+	m.observe("TokenGeneration", d, err)
+}
+
+func (m loggerMetrics) observe(op string, d time.Duration, err error) {
+	m.logger.WithField("operation", op).WithField("latency", d).Debugf("Observed success=%t", err == nil)
+}
+
+// metrics returns Metrics or a no-op default. It is safe to call on a nil
+// config.
+func (c *ClusterConfig) metrics() Metrics {
+	if c == nil || c.Metrics == nil {
+		return NoopMetrics{}
+	}
+	return c.Metrics
+}
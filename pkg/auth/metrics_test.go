@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics counts observations and failures per phase.
+type recordingMetrics struct {
+	mu       sync.Mutex
+	counts   map[string]int
+	failures map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{counts: map[string]int{}, failures: map[string]int{}}
+}
+
+func (m *recordingMetrics) ObserveDescribeCluster(d time.Duration, err error) {
+	m.observe("DescribeCluster", err)
+}
+
+func (m *recordingMetrics) ObserveGetCallerIdentity(d time.Duration, err error) {
+	m.observe("GetCallerIdentity", err)
+}
+
+func (m *recordingMetrics) ObserveTokenGeneration(d time.Duration, err error) {
+	m.observe("TokenGeneration", err)
+}
+
+func (m *recordingMetrics) observe(phase string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[phase]++
+	if err != nil {
+		m.failures[phase]++
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := newRecordingMetrics()
+	config := testConfig(t, nil, nil)
+	config.Metrics = metrics
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range []string{"DescribeCluster", "GetCallerIdentity", "TokenGeneration"} {
+		if n := metrics.counts[phase]; n != 1 {
+			t.Errorf("%d observations of %s, want 1", n, phase)
+		}
+		if n := metrics.failures[phase]; n != 0 {
+			t.Errorf("%d failures of %s, want 0", n, phase)
+		}
+	}
+}
+
+func TestMetricsFailure(t *testing.T) {
+	fake := newFakeAWS()
+	metrics := newRecordingMetrics()
+	config := testConfig(t, fake, nil)
+	config.Metrics = metrics
+	fake.fail("GetCallerIdentity", 1, http.StatusForbidden, "AccessDenied")
+
+	if _, err := NewAuthClient(config); err == nil {
+		t.Fatal("NewAuthClient() = nil error")
+	}
+	if metrics.counts["GetCallerIdentity"] != 1 || metrics.failures["GetCallerIdentity"] != 1 {
+		t.Errorf("GetCallerIdentity observed %d times with %d failures, want 1 failure",
+			metrics.counts["GetCallerIdentity"], metrics.failures["GetCallerIdentity"])
+	}
+	if n := metrics.counts["TokenGeneration"]; n != 0 {
+		t.Errorf("%d observations of TokenGeneration after the failed check, want 0", n)
+	}
+}
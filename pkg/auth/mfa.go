@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrNoMFATokenProvider is returned when an assumed role requires an MFA
+// token code, no MFAPromptFunc is set and stdin is not a terminal.
+var ErrNoMFATokenProvider = errors.New("MFA token code required but stdin is not a terminal, set MFAPromptFunc")
+
+// StdinMFAPrompt returns an MFA prompt function for ClusterConfig.MFAPromptFunc
+// that waits for delay, prints prompt to stderr and reads the token code from
+// stdin.
+func StdinMFAPrompt(prompt string, delay time.Duration) func() (string, error) {
+	if prompt == "" {
+		prompt = "Assume Role MFA token code: "
+	}
+	return func() (string, error) {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		fmt.Fprint(os.Stderr, prompt)
+
+		code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return "", errors.Wrap(err, "reading MFA token code")
+		}
+		return strings.TrimSpace(code), nil
+	}
+}
+
+// mfaTokenProvider returns the configured MFA token provider, falling back to
+// a stdin prompt when stdin is a terminal.
+func (c *ClusterConfig) mfaTokenProvider() func() (string, error) {
+	switch {
+	case c.MFAPromptFunc != nil:
+		return c.MFAPromptFunc
+	case stdinIsTerminal():
+		return stscreds.StdinTokenProvider
+	}
+	return func() (string, error) {
+		return "", ErrNoMFATokenProvider
+	}
+}
+
+// stdinIsTerminal reports whether stdin is a terminal. Checking for a
+// character device is not enough, /dev/null is one too.
+func stdinIsTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// newMFATestConfig returns a config using a shared config profile that
+// assumes a role requiring MFA.
+func newMFATestConfig(t *testing.T, fake *fakeAWS) *ClusterConfig {
+	t.Helper()
+	config := newTestSessionConfig(t, fake)
+	config.Profile = "admin"
+	sharedConfig := "[profile admin]\n" +
+		"role_arn = arn:aws:iam::111122223333:role/Admin\n" +
+		"source_profile = base\n" +
+		"mfa_serial = arn:aws:iam::111122223333:mfa/tester\n"
+	credentialsFile := "[base]\naws_access_key_id = " + testAccessKeyID + "\naws_secret_access_key = secret\n"
+	if err := ioutil.WriteFile(os.Getenv("AWS_CONFIG_FILE"), []byte(sharedConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), []byte(credentialsFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestMFAPromptFunc(t *testing.T) {
+	fake := newFakeAWS()
+	config := newMFATestConfig(t, fake)
+	prompts := 0
+	config.MFAPromptFunc = func() (string, error) {
+		prompts++
+		return "123456", nil
+	}
+
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	calls := fake.callsTo("AssumeRole")
+	if len(calls) != 1 {
+		t.Fatalf("AssumeRole called %d times, want 1", len(calls))
+	}
+	if got := calls[0].Params.Get("TokenCode"); got != "123456" || prompts != 1 {
+		t.Errorf("AssumeRole TokenCode = %q after %d prompts, want 123456 from one prompt", got, prompts)
+	}
+	if got := calls[0].Params.Get("SerialNumber"); got != "arn:aws:iam::111122223333:mfa/tester" {
+		t.Errorf("AssumeRole SerialNumber = %q", got)
+	}
+}
+
+func TestMFAWithoutTerminal(t *testing.T) {
+	fake := newFakeAWS()
+	config := newMFATestConfig(t, fake)
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = devNull
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		devNull.Close()
+	})
+
+	if _, err := NewAuthClient(config); !errors.Is(err, ErrNoMFATokenProvider) {
+		t.Errorf("NewAuthClient() = %v, want %v", err, ErrNoMFATokenProvider)
+	}
+	if calls := fake.callsTo("AssumeRole"); len(calls) != 0 {
+		t.Errorf("AssumeRole called %d times without a token code", len(calls))
+	}
+}
+
+// redirectStdio replaces stdin with a file holding input and stderr with a
+// temporary file, which is returned.
+func redirectStdio(t *testing.T, input string) *os.File {
+	t.Helper()
+	stdinFile := writeTestFile(t, "stdin", []byte(input))
+	newStdin, err := os.Open(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newStderr, err := ioutil.TempFile(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, stderr := os.Stdin, os.Stderr
+	os.Stdin, os.Stderr = newStdin, newStderr
+	t.Cleanup(func() {
+		os.Stdin, os.Stderr = stdin, stderr
+		newStdin.Close()
+		newStderr.Close()
+	})
+	return newStderr
+}
+
+func stderrOutput(t *testing.T, stderr *os.File) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStdinMFAPrompt(t *testing.T) {
+	fake := newFakeAWS()
+	config := newMFATestConfig(t, fake)
+	stderr := redirectStdio(t, " 654321 \n")
+	config.MFAPromptFunc = StdinMFAPrompt("Code for tester: ", 50*time.Millisecond)
+
+	start := time.Now()
+	if _, err := NewAuthClient(config); err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("prompted after %s, want a delay of at least 50ms", elapsed)
+	}
+	if got := stderrOutput(t, stderr); got != "Code for tester: " {
+		t.Errorf("stderr = %q, want the custom prompt", got)
+	}
+	calls := fake.callsTo("AssumeRole")
+	if len(calls) != 1 || calls[0].Params.Get("TokenCode") != "654321" {
+		t.Errorf("AssumeRole calls = %+v, want one with the trimmed token code", calls)
+	}
+}
+
+func TestStdinMFAPromptDefault(t *testing.T) {
+	stderr := redirectStdio(t, "123456\n")
+	code, err := StdinMFAPrompt("", 0)()
+	if err != nil || code != "123456" {
+		t.Errorf("prompt = %q, %v, want 123456", code, err)
+	}
+	if got := stderrOutput(t, stderr); got != "Assume Role MFA token code: " {
+		t.Errorf("stderr = %q, want the default prompt", got)
+	}
+}
+
+func TestStdinMFAPromptNoInput(t *testing.T) {
+	redirectStdio(t, "")
+	if _, err := StdinMFAPrompt("", 0)(); err == nil {
+		t.Error("prompt = nil error for empty stdin")
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/pkg/errors"
+)
+
+// ListNodegroups returns the names of the managed nodegroups of the cluster,
+// which may be empty. It requires eks:ListNodegroups.
+func (c *ClusterConfig) ListNodegroups() ([]string, error) {
+	if err := c.resolveClusterName(); err != nil {
+		return nil, err
+	}
+	if err := c.ensureSession(); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	input := &eks.ListNodegroupsInput{ClusterName: aws.String(c.ClusterName)}
+	err := c.eksAPI().ListNodegroupsPages(input, func(page *eks.ListNodegroupsOutput, lastPage bool) bool {
+		names = append(names, aws.StringValueSlice(page.Nodegroups)...)
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing nodegroups of cluster %s", c.clusterID())
+	}
+	return names, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+// fakeNodegroupsEKS serves nodegroups one page at a time.
+type fakeNodegroupsEKS struct {
+	*fakeEKS
+	pages [][]string
+}
+
+func (f *fakeNodegroupsEKS) ListNodegroupsPages(input *eks.ListNodegroupsInput, fn func(*eks.ListNodegroupsOutput, bool) bool) error {
+	if len(f.pages) == 0 {
+		fn(&eks.ListNodegroupsOutput{}, true)
+		return nil
+	}
+	for i, page := range f.pages {
+		if !fn(&eks.ListNodegroupsOutput{Nodegroups: aws.StringSlice(page)}, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestListNodegroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages [][]string
+		want  []string
+	}{
+		{name: "paged", pages: [][]string{{"system", "workers"}, {"spot"}}, want: []string{"system", "workers", "spot"}},
+		{name: "none", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeNodegroupsEKS{fakeEKS: newFakeEKS(), pages: tt.pages}
+			config := &ClusterConfig{ClusterName: testClusterName, Session: newFakeAWS().session(t), EKS: svc, Logger: newTestLogger()}
+
+			names, err := config.ListNodegroups()
+			if err != nil {
+				t.Fatalf("ListNodegroups() = %v", err)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("ListNodegroups() = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/transport"
+)
+
+// proxyWrapper returns a transport wrapper that sends requests through the
+// proxy at proxyURL. The wrapped transport must be the *http.Transport built
+// by client-go. It is cloned rather than modified, since client-go caches it
+// for other clients; the clone is not cached, so every client created with a
+// proxy has its own connection pool.
+func proxyWrapper(proxyURL string, logger Logger) (transport.WrapperFunc, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proxy URL %q", proxyURL)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("invalid proxy URL %q: scheme and host are required", proxyURL)
+	}
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		t, ok := rt.(*http.Transport)
+		if !ok {
+			logger.Warnf("Cannot use proxy %s with transport %T", u.Redacted(), rt)
+			return rt
+		}
+		t = t.Clone()
+		t.Proxy = http.ProxyURL(u)
+		return t
+	}, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+func TestProxyURL(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.ProxyURL = "http://proxy.example.com:3128"
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	restConfig, err := client.NewRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := rest.TransportFor(restConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ok := innermostTransport(rt).(*http.Transport)
+	if !ok {
+		t.Fatalf("innermost transport is %T, want *http.Transport", innermostTransport(rt))
+	}
+	if tr.Proxy == nil {
+		t.Fatal("transport has no proxy")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, client.Endpoint()+"/api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u == nil || u.String() != config.ProxyURL {
+		t.Errorf("Proxy() = %v, want %s", u, config.ProxyURL)
+	}
+}
+
+func TestProxyURLInvalid(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	client := lookedUpClientConfig(t, config)
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	config.ProxyURL = "proxy.example.com"
+	if _, err := client.NewRESTConfig(); err == nil {
+		t.Error("NewRESTConfig() = nil error for a proxy URL without a scheme")
+	}
+}
+
+// innermostTransport returns the transport wrapped by the client-go wrappers
+// of rt.
+func innermostTransport(rt http.RoundTripper) http.RoundTripper {
+	for {
+		wrapper, ok := rt.(utilnet.RoundTripperWrapper)
+		if !ok {
+			return rt
+		}
+		rt = wrapper.WrappedRoundTripper()
+	}
+}
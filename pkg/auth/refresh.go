@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// RefreshClusterCA looks up the cluster again and updates the endpoint and
+// certificate authority data on the config.
+func (c *ClusterConfig) RefreshClusterCA() error {
+	return c.RefreshClusterCAWithContext(context.Background())
+}
+
+// RefreshClusterCAWithContext is like RefreshClusterCA, with the context
+// bounding the cluster lookup.
+func (c *ClusterConfig) RefreshClusterCAWithContext(ctx context.Context) error {
+	if err := c.ensureSession(); err != nil {
+		return err
+	}
+	return c.loadConfig(ctx)
+}
+
+// caRefreshWrapper returns a transport wrapper that refreshes the cluster CA
+// and retries a request once when it fails with an unknown authority error.
+func (c *ClientConfig) caRefreshWrapper(config *rest.Config) transport.WrapperFunc {
+	// The transport is rebuilt with the wrappers applied before this one.
+	base := rest.CopyConfig(config)
+
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &caRefreshRoundTripper{
+			client: c,
+			config: base,
+			rt:     rt,
+		}
+	}
+}
+
+type caRefreshRoundTripper struct {
+	client *ClientConfig
+	config *rest.Config
+
+	mu sync.Mutex
+	rt http.RoundTripper
+}
+
+func (t *caRefreshRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	rt := t.rt
+	t.mu.Unlock()
+
+	resp, err := rt.RoundTrip(req)
+	if err == nil || !isUnknownAuthority(err) {
+		return resp, err
+	}
+
+	retry := req
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return resp, err
+		}
+		body, berr := req.GetBody()
+		if berr != nil {
+			return resp, err
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+
+	t.client.cluster.logger().WithField("cluster", t.client.clusterID()).Infof("Server certificate signed by unknown authority, refreshing cluster CA")
+
+	// Another request may already have refreshed the CA.
+	t.mu.Lock()
+	if t.rt == rt {
+		if rerr := t.refresh(req.Context()); rerr != nil {
+			t.mu.Unlock()
+			t.client.cluster.logger().WithField("cluster", t.client.clusterID()).Errorf("%s", rerr)
+			return resp, err
+		}
+	}
+	rt = t.rt
+	t.mu.Unlock()
+
+	return rt.RoundTrip(retry)
+}
+
+// refresh looks up the cluster on a copy of the cluster config and builds a
+// new transport for the endpoint and CA found. The client and cluster configs
+// are shared with the code making requests, so neither is written to. It must
+// be called with t.mu held.
+func (t *caRefreshRoundTripper) refresh(ctx context.Context) error {
+	if t.client.cluster == nil {
+		return errors.New("client config was not created by NewClientConfig")
+	}
+
+	cluster := *t.client.cluster
+	if err := cluster.RefreshClusterCAWithContext(ctx); err != nil {
+		return errors.Wrap(err, "refreshing cluster CA")
+	}
+	data, err := cluster.caBundle()
+	if err != nil {
+		return err
+	}
+
+	config := rest.CopyConfig(t.config)
+	config.Host = cluster.server()
+	config.TLSClientConfig.CAData = data
+	config.TLSClientConfig.CAFile = ""
+
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		return errors.Wrap(err, "creating transport with refreshed CA")
+	}
+
+	t.config = config
+	t.rt = rt
+	return nil
+}
+
+// Refresh looks up the cluster again and updates the endpoint and CA of the
+// config in place, e.g. after the cluster CA was rotated. It reports whether
+// either changed. Clientsets already created keep using the old values.
+func (c *ClientConfig) Refresh() (bool, error) {
+	return c.RefreshWithContext(context.Background())
+}
+
+// RefreshWithContext is like Refresh, with the context bounding the cluster
+// lookup.
+func (c *ClientConfig) RefreshWithContext(ctx context.Context) (bool, error) {
+	kc := c.currentCluster()
+	if c.cluster == nil || kc == nil {
+		return false, errors.New("client config was not created by NewClientConfig")
+	}
+
+	cluster := c.cluster
+	if err := cluster.RefreshClusterCAWithContext(ctx); err != nil {
+		return false, errors.Wrap(err, "refreshing cluster CA")
+	}
+
+	data, err := cluster.caBundle()
+	if err != nil {
+		return false, err
+	}
+
+	changed := kc.Server != cluster.server() || !bytes.Equal(kc.CertificateAuthorityData, data)
+	kc.Server = cluster.server()
+	kc.CertificateAuthorityData = data
+	return changed, nil
+}
+
+func isUnknownAuthority(err error) bool {
+	var uaErr x509.UnknownAuthorityError
+	if stderrors.As(err, &uaErr) {
+		return true
+	}
+	return strings.Contains(err.Error(), "certificate signed by unknown authority")
+}
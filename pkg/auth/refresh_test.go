@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestRefresh(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	client := lookedUpClientConfig(t, testConfig(t, fake, api))
+
+	changed, err := client.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	if changed {
+		t.Error("Refresh() reported a change for the same cluster")
+	}
+
+	rotated := testCAPEM(t, "rotated CA")
+	fake.addCluster(testClusterName, api.URL, base64.StdEncoding.EncodeToString(rotated))
+	if changed, err = client.Refresh(); err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	if !changed {
+		t.Error("Refresh() did not report the rotated CA")
+	}
+	if got := client.CACertificate(); !bytes.Equal(got, rotated) {
+		t.Errorf("CACertificate() = %q, want the rotated CA", got)
+	}
+	if n := len(fake.callsTo("DescribeCluster")); n != 3 {
+		t.Errorf("DescribeCluster called %d times, want once per lookup", n)
+	}
+}
+
+// staleCAClient returns a client config for api with AutoRefreshCA set,
+// looked up while DescribeCluster returned a CA that did not sign the server
+// certificate. DescribeCluster returns the server's real CA afterwards.
+func staleCAClient(t *testing.T, fake *fakeAWS, api *fakeAPIServer) *ClientConfig {
+	t.Helper()
+	config := testConfig(t, fake, api)
+	config.AutoRefreshCA = true
+	fake.addCluster(testClusterName, api.URL, base64.StdEncoding.EncodeToString(testCAPEM(t, "stale CA")))
+	client := lookedUpClientConfig(t, config)
+	fake.addCluster(testClusterName, api.URL, api.caData())
+	return client
+}
+
+func TestAutoRefreshCA(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	client := staleCAClient(t, fake, api)
+	stale := client.CACertificate()
+
+	cs, err := client.NewClientSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests failing at the same time share one refresh, while the
+	// client config is read as usual.
+	const requests = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cs.Discovery().ServerVersion()
+			errs <- err
+			client.Endpoint()
+			client.CACertificate()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ServerVersion() = %v", err)
+		}
+	}
+
+	if n := len(fake.callsTo("DescribeCluster")); n != 2 {
+		t.Errorf("DescribeCluster called %d times, want the lookup and one refresh", n)
+	}
+	// Requests with the stale CA fail the handshake, so the server only
+	// sees the retries.
+	if n := len(api.requestTokens()); n != requests {
+		t.Errorf("API server got %d requests, want one retry per request", n)
+	}
+
+	if _, err := cs.Discovery().ServerVersion(); err != nil {
+		t.Fatalf("ServerVersion() after refresh = %v", err)
+	}
+	if n := len(fake.callsTo("DescribeCluster")); n != 2 {
+		t.Errorf("DescribeCluster called %d times after refresh, want no further refresh", n)
+	}
+	if n := len(api.requestTokens()); n != requests+1 {
+		t.Errorf("API server got %d requests after refresh, want %d", n, requests+1)
+	}
+
+	if got := client.CACertificate(); !bytes.Equal(got, stale) {
+		t.Error("refreshing the transport changed the CA of the client config")
+	}
+}
+
+func TestAutoRefreshCAReplaysBody(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	client := staleCAClient(t, fake, api)
+
+	config, err := client.restConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const body = `{"kind":"Namespace","apiVersion":"v1","metadata":{"name":"test"}}`
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/api/v1/namespaces", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	if got := api.postedBodies(); len(got) != 1 || got[0] != body {
+		t.Errorf("posted bodies = %q, want the request body once", got)
+	}
+	if n := len(fake.callsTo("DescribeCluster")); n != 2 {
+		t.Errorf("DescribeCluster called %d times, want the lookup and one refresh", n)
+	}
+}
+
+func TestAutoRefreshCAWithoutGetBody(t *testing.T) {
+	fake := newFakeAWS()
+	api := newFakeAPIServer(t)
+	client := staleCAClient(t, fake, api)
+
+	config, err := client.restConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/api/v1/namespaces", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = nil
+	if _, err := rt.RoundTrip(req); err == nil || !isUnknownAuthority(err) {
+		t.Fatalf("RoundTrip() = %v, want the unknown authority error", err)
+	}
+	if n := len(fake.callsTo("DescribeCluster")); n != 1 {
+		t.Errorf("DescribeCluster called %d times, want no refresh for a body that cannot be replayed", n)
+	}
+	if got := api.postedBodies(); len(got) != 0 {
+		t.Errorf("posted bodies = %q, want none", got)
+	}
+}
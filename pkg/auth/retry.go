@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/client-go/transport"
+)
+
+const (
+	defaultServerErrorRetries = 3
+	defaultRetryAfter         = time.Second
+	maxRetryAfter             = 30 * time.Second
+)
+
+// serverErrorRetrier returns a transport wrapper that retries idempotent
+// requests answered with 429 or 503.
+func serverErrorRetrier(retries int, logger Logger) transport.WrapperFunc {
+	if retries <= 0 {
+		retries = defaultServerErrorRetries
+	}
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &serverErrorRoundTripper{retries: retries, logger: logger, delay: retryAfter, rt: rt}
+	}
+}
+
+type serverErrorRoundTripper struct {
+	retries int
+	logger  Logger
+	delay   func(*http.Response) time.Duration
+	rt      http.RoundTripper
+}
+
+func (t *serverErrorRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if !idempotent(req) {
+		return resp, err
+	}
+
+	for attempt := 1; attempt <= t.retries && err == nil && retryableStatus(resp.StatusCode); attempt++ {
+		wait := t.delay(resp)
+		resp.Body.Close()
+
+		t.logger.
+			WithField("status", resp.StatusCode).
+			WithField("path", req.URL.Path).
+			WithField("attempt", attempt).
+			Warnf("API server busy, retrying in %s", wait)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		resp, err = t.rt.RoundTrip(req)
+	}
+	return resp, err
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// retryAfter returns the delay requested by the Retry-After header in seconds,
+// capped at maxRetryAfter.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return defaultRetryAfter
+	}
+	wait := time.Duration(seconds) * time.Second
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serverErrorServer answers requests with the statuses given, and 200 OK once
+// they run out. It counts the requests received.
+func serverErrorServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n > len(statuses) {
+			w.Write([]byte("ok"))
+			return
+		}
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		http.Error(w, http.StatusText(statuses[n-1]), statuses[n-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// testServerErrorRetrier returns a retrying transport that records the delays
+// asked for instead of waiting.
+func testServerErrorRetrier(retries int) (*serverErrorRoundTripper, *[]time.Duration) {
+	rt := serverErrorRetrier(retries, newTestLogger())(http.DefaultTransport).(*serverErrorRoundTripper)
+	var delays []time.Duration
+	rt.delay = func(resp *http.Response) time.Duration {
+		delays = append(delays, retryAfter(resp))
+		return 0
+	}
+	return rt, &delays
+}
+
+func TestServerErrorRetrier(t *testing.T) {
+	srv, requests := serverErrorServer(t, "2", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	rt, delays := testServerErrorRetrier(0)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Errorf("server got %d requests, want 3", n)
+	}
+	if len(*delays) != 2 || (*delays)[0] != 2*time.Second || (*delays)[1] != 2*time.Second {
+		t.Errorf("delays = %v, want the Retry-After of both busy responses", *delays)
+	}
+}
+
+func TestServerErrorRetrierCap(t *testing.T) {
+	srv, requests := serverErrorServer(t, "", http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+	rt, _ := testServerErrorRetrier(2)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want the last busy response", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Errorf("server got %d requests, want the request and 2 retries", n)
+	}
+}
+
+func TestServerErrorRetrierPassesThrough(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"POST", http.MethodPost, "{}", http.StatusServiceUnavailable},
+		{"PATCH", http.MethodPatch, "{}", http.StatusTooManyRequests},
+		{"GET with body", http.MethodGet, "{}", http.StatusServiceUnavailable},
+		{"not found", http.MethodGet, "", http.StatusNotFound},
+		{"forbidden", http.MethodGet, "", http.StatusForbidden},
+		{"server error", http.MethodGet, "", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := serverErrorServer(t, "", tt.status)
+			rt, delays := testServerErrorRetrier(0)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() = %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if data, _ := ioutil.ReadAll(resp.Body); !strings.Contains(string(data), http.StatusText(tt.status)) {
+				t.Errorf("body = %q, want the server response", data)
+			}
+			if n := atomic.LoadInt32(requests); n != 1 {
+				t.Errorf("server got %d requests, want 1", n)
+			}
+			if len(*delays) != 0 {
+				t.Errorf("delays = %v, want none", *delays)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", defaultRetryAfter},
+		{"5", 5 * time.Second},
+		{"0", defaultRetryAfter},
+		{"-3", defaultRetryAfter},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", defaultRetryAfter},
+		{"120", maxRetryAfter},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		if got := retryAfter(resp); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
+)
+
+// TokenFromCredentials generates a token for the cluster signed with the
+// given credentials, e.g. STS credentials fetched ahead of time. It makes no
+// network calls and ignores Session, so only ClusterName, Region and the STS
+// options of the config are needed; Region is required.
+func (c *ClusterConfig) TokenFromCredentials(creds credentials.Value) (string, time.Time, error) {
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" || creds.SessionToken == "" {
+		return "", time.Time{}, errors.New("access key ID, secret access key and session token are required")
+	}
+	if err := c.resolveClusterName(); err != nil {
+		return "", time.Time{}, err
+	}
+	if c.Region == "" {
+		return "", time.Time{}, errors.New("Region cannot be empty")
+	}
+	ttl, err := c.tokenCacheTTL()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config: *aws.NewConfig().
+			WithRegion(c.Region).
+			WithCredentials(credentials.NewStaticCredentialsFromCreds(creds)),
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(err, "creating AWS session")
+	}
+	stsAPI := sts.New(sess, c.stsConfig())
+
+	gen, err := token.NewGenerator(false, false)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(withKind(ErrTokenGeneration, err), "could not get token generator")
+	}
+	tok, err := gen.GetWithSTS(c.ClusterName, stsAPI)
+	if err != nil {
+		return "", time.Time{}, errors.Wrap(withKind(ErrTokenGeneration, err), "could not get token")
+	}
+	if ttl > 0 {
+		tok.Expiration = time.Now().Add(ttl)
+	}
+	return tok.Token, tok.Expiration, nil
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func TestTokenFromCredentials(t *testing.T) {
+	isolateAWSEnv(t)
+	config := &ClusterConfig{ClusterName: testClusterName, Region: testRegion}
+	creds := credentials.Value{AccessKeyID: "ASIAOFFLINE", SecretAccessKey: "secret", SessionToken: "session"}
+
+	tok, expiry, err := config.TokenFromCredentials(creds)
+	if err != nil {
+		t.Fatalf("TokenFromCredentials() = %v", err)
+	}
+	if got := tokenAccessKeyID(t, tok); got != "ASIAOFFLINE" {
+		t.Errorf("token signed by %q, want ASIAOFFLINE", got)
+	}
+	if !expiry.After(time.Now()) {
+		t.Errorf("token expiry %s is not in the future", expiry)
+	}
+
+	for _, missing := range []credentials.Value{
+		{SecretAccessKey: "secret", SessionToken: "session"},
+		{AccessKeyID: "ASIAOFFLINE", SessionToken: "session"},
+		{AccessKeyID: "ASIAOFFLINE", SecretAccessKey: "secret"},
+	} {
+		if _, _, err := config.TokenFromCredentials(missing); err == nil {
+			t.Errorf("TokenFromCredentials(%+v) = nil error", missing)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	// ErrGlobalSTSEndpoint is returned in strict mode when STS requests would
+	// go to the global sts.amazonaws.com endpoint instead of a regional one.
+	// Set STSRegion or enable regional STS endpoints on the session.
+	ErrGlobalSTSEndpoint = errors.New("global STS endpoint in use, configure a regional STS endpoint")
+
+	// ErrEmbeddedToken is returned in strict mode when a kubeconfig carrying a
+	// static bearer token would be written out of the process, where it may
+	// outlive the process and leak.
+	ErrEmbeddedToken = errors.New("kubeconfig contains an embedded static token")
+
+	// ErrInsecureTLS is returned in strict mode when the API server
+	// certificate would not be verified against a certificate authority.
+	ErrInsecureTLS = errors.New("API server certificate is not verified")
+)
+
+// strict returns err in strict mode and otherwise logs it as a warning. It is
+// safe to call on a nil config.
+func (c *ClusterConfig) strict(err error) error {
+	if c != nil && c.StrictMode {
+		return errors.Wrap(err, "strict mode")
+	}
+	c.logger().Warnf("%s", err)
+	return nil
+}
+
+func (c *ClusterConfig) checkSTSEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() != "sts.amazonaws.com" {
+		return nil
+	}
+	return c.strict(ErrGlobalSTSEndpoint)
+}
+
+func (c *ClusterConfig) checkTLS(config *rest.Config) error {
+	tls := config.TLSClientConfig
+	if !tls.Insecure && (len(tls.CAData) > 0 || tls.CAFile != "") {
+		return nil
+	}
+	return c.strict(ErrInsecureTLS)
+}
+
+// checkEmbeddedToken must be called before a kubeconfig leaves the process.
+func (c *ClientConfig) checkEmbeddedToken() error {
+	for _, authInfo := range c.Client.AuthInfos {
+		if authInfo.Token != "" {
+			return c.cluster.strict(ErrEmbeddedToken)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+func TestStrictChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(*ClusterConfig) error
+		want  error
+	}{
+		{"global STS endpoint", func(c *ClusterConfig) error {
+			return c.checkSTSEndpoint("https://sts.amazonaws.com")
+		}, ErrGlobalSTSEndpoint},
+		{"insecure TLS", func(c *ClusterConfig) error {
+			return c.checkTLS(&rest.Config{TLSClientConfig: rest.TLSClientConfig{Insecure: true}})
+		}, ErrInsecureTLS},
+		{"no CA", func(c *ClusterConfig) error {
+			return c.checkTLS(&rest.Config{})
+		}, ErrInsecureTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := newTestLogger()
+			err := tt.check(&ClusterConfig{StrictMode: true, Logger: logger})
+			if errors.Cause(err) != tt.want {
+				t.Errorf("strict mode error = %v, want %v", err, tt.want)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), "strict mode: ") {
+				t.Errorf("strict mode error %q does not say it is from strict mode", err)
+			}
+
+			if err := tt.check(&ClusterConfig{Logger: logger}); err != nil {
+				t.Errorf("error = %v without strict mode, want a warning", err)
+			}
+			entry, ok := logger.find("warn", tt.want.Error())
+			if !ok {
+				t.Fatal("no warning logged without strict mode")
+			}
+			if strings.Contains(entry.Message, "strict mode") {
+				t.Errorf("warning %q mentions strict mode, which is off", entry.Message)
+			}
+		})
+	}
+}
+
+func TestStrictChecksPass(t *testing.T) {
+	config := &ClusterConfig{StrictMode: true}
+	if err := config.checkSTSEndpoint("https://sts.us-west-2.amazonaws.com"); err != nil {
+		t.Errorf("regional STS endpoint: %v", err)
+	}
+	if err := config.checkTLS(&rest.Config{TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")}}); err != nil {
+		t.Errorf("verified TLS: %v", err)
+	}
+}
+
+func TestStrictModeEmbeddedToken(t *testing.T) {
+	config := testConfig(t, nil, nil)
+	config.StrictMode = true
+	client, err := config.NewClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TokenGenerator = &fakeTokenGenerator{}
+
+	err = client.WriteKubeconfig(filepath.Join(t.TempDir(), "kubeconfig"))
+	if errors.Cause(err) != ErrEmbeddedToken {
+		t.Errorf("WriteKubeconfig() = %v, want %v", err, ErrEmbeddedToken)
+	}
+
+	exec, err := client.WithExecCredential("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.WriteKubeconfig(filepath.Join(t.TempDir(), "kubeconfig")); err != nil {
+		t.Errorf("WriteKubeconfig() with an exec credential = %v", err)
+	}
+}
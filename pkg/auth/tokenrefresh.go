@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+	"sync"
+
+	"k8s.io/client-go/transport"
+)
+
+// tokenRefreshWrapper returns a transport wrapper that generates a new token
+// and retries a request once when it is answered with 401 Unauthorized.
+func (c *ClientConfig) tokenRefreshWrapper() transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &tokenRefreshRoundTripper{client: c, rt: rt}
+	}
+}
+
+type tokenRefreshRoundTripper struct {
+	client *ClientConfig
+	rt     http.RoundTripper
+
+	mu    sync.Mutex
+	token string // replaces the embedded token once refreshed
+}
+
+func (t *tokenRefreshRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	tok := t.token
+	t.mu.Unlock()
+
+	resp, err := t.rt.RoundTrip(t.withToken(req, tok))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	retry := req
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return resp, err
+		}
+		body, berr := req.GetBody()
+		if berr != nil {
+			return resp, err
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+
+	t.client.cluster.logger().WithField("cluster", t.client.clusterID()).Infof("Token rejected, generating a new token")
+
+	// Another request may already have refreshed the token.
+	t.mu.Lock()
+	if t.token == tok {
+		newTok, gerr := t.client.generateToken()
+		if gerr != nil {
+			t.mu.Unlock()
+			t.client.cluster.logger().WithField("cluster", t.client.clusterID()).Errorf("%s", gerr)
+			return resp, err
+		}
+		t.token = newTok.Token
+	}
+	tok = t.token
+	t.mu.Unlock()
+
+	resp.Body.Close()
+	return t.rt.RoundTrip(t.withToken(retry, tok))
+}
+
+func (t *tokenRefreshRoundTripper) withToken(req *http.Request, tok string) *http.Request {
+	if tok == "" {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return req
+}
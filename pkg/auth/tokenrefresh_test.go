@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoRefreshToken(t *testing.T) {
+	api := newFakeAPIServer(t)
+	first := "k8s-aws-v1." + testClusterName + "-1"
+	second := "k8s-aws-v1." + testClusterName + "-2"
+	api.reject = func(tok string) bool { return tok == first }
+
+	config := testConfig(t, nil, api)
+	config.AutoRefreshToken = true
+	client := lookedUpClientConfig(t, config)
+	gen := &fakeTokenGenerator{}
+	client.TokenGenerator = gen
+
+	cs, err := client.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.CoreV1().Namespaces().List(metav1.ListOptions{}); err != nil {
+		t.Fatalf("List() = %v, want success after refreshing the token", err)
+	}
+	if n := gen.generated(); n != 2 {
+		t.Errorf("%d tokens generated, want the embedded one and one regeneration", n)
+	}
+
+	// Later requests use the new token without another round trip.
+	if _, err := cs.CoreV1().Namespaces().List(metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{first, second, second}; !reflect.DeepEqual(api.requestTokens(), want) {
+		t.Errorf("API server got tokens %q, want %q", api.requestTokens(), want)
+	}
+	if n := gen.generated(); n != 2 {
+		t.Errorf("%d tokens generated after a second request, want 2", n)
+	}
+}
+
+func TestAutoRefreshTokenDisabled(t *testing.T) {
+	api := newFakeAPIServer(t)
+	api.reject = func(string) bool { return true }
+	client := lookedUpClientConfig(t, testConfig(t, nil, api))
+	gen := &fakeTokenGenerator{}
+	client.TokenGenerator = gen
+
+	cs, err := client.NewClientSetWithEmbeddedToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.CoreV1().Namespaces().List(metav1.ListOptions{}); err == nil {
+		t.Fatal("List() = nil error for a rejected token")
+	}
+	if n := gen.generated(); n != 1 {
+		t.Errorf("%d tokens generated without AutoRefreshToken, want 1", n)
+	}
+}
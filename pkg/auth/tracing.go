@@ -0,0 +1,49 @@
+package auth
+
+import "context"
+
+// Tracer starts spans around the cluster lookup, the caller identity check
+// and token generation, e.g. an adapter to an OpenTelemetry trace.Tracer.
+// Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start starts a span that is a child of the span in ctx, if any.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// NoopTracer is a Tracer whose spans record nothing.
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+
+// trace runs fn in a span named "eksutil." + name that records the cluster
+// and the error returned by fn. It is safe to call on a nil config.
+func (c *ClusterConfig) trace(ctx context.Context, name string, fn func(context.Context) error) error {
+	if c == nil || c.Tracer == nil {
+		return fn(ctx)
+	}
+
+	ctx, span := c.Tracer.Start(ctx, "eksutil."+name)
+	defer span.End()
+	span.SetAttribute("eks.cluster", c.clusterID())
+
+	err := fn(ctx)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
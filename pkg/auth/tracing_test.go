@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type parentKey struct{}
+
+// recordingTracer keeps the spans it starts in memory.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	errs   []error
+	ended  bool
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(parentKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return context.WithValue(ctx, parentKey{}, name), span
+}
+
+func (r *recordingTracer) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, s := range r.spans {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.errs = append(s.errs, err) }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	config := testConfig(t, nil, nil)
+	config.Tracer = tracer
+
+	ctx := context.WithValue(context.Background(), parentKey{}, "handler")
+	if _, err := NewAuthClientWithContext(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"eksutil.DescribeCluster", "eksutil.GetCallerIdentity", "eksutil.GetToken"}
+	if got := tracer.names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("spans = %q, want %q", got, want)
+	}
+	for _, span := range tracer.spans {
+		if span.parent != "handler" {
+			t.Errorf("%s is a child of %q, want the span of the caller context", span.name, span.parent)
+		}
+		if span.attrs["eks.cluster"] != testClusterName || len(span.errs) != 0 || !span.ended {
+			t.Errorf("%s = %+v, want an ended span for the cluster without errors", span.name, span)
+		}
+	}
+}
+
+func TestTracerRecordsErrors(t *testing.T) {
+	fake := newFakeAWS()
+	tracer := &recordingTracer{}
+	config := testConfig(t, fake, nil)
+	config.Tracer = tracer
+	fake.fail("GetCallerIdentity", 1, http.StatusForbidden, "AccessDenied")
+
+	if _, err := NewAuthClient(config); err == nil {
+		t.Fatal("NewAuthClient() = nil error")
+	}
+	if got, want := tracer.names(), []string{"eksutil.DescribeCluster", "eksutil.GetCallerIdentity"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("spans = %q, want %q", got, want)
+	}
+	if span := tracer.spans[1]; len(span.errs) != 1 || !span.ended {
+		t.Errorf("%s recorded %d errors, want the STS error", span.name, len(span.errs))
+	}
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Version is the version of eksutil reported in the default user agent. It
+// can be set at build time with -ldflags "-X github.com/chankh/eksutil/pkg/auth.Version=...".
+var Version = "dev"
+
+// userAgent returns UserAgent or the eksutil default. It is safe to call on
+// a nil config.
+func (c *ClusterConfig) userAgent() string {
+	if c != nil && c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return "eksutil/" + Version
+}
+
+// addUserAgent appends the user agent to requests of an AWS client.
+func (c *ClusterConfig) addUserAgent(handlers *request.Handlers) {
+	handlers.Build.PushBack(request.MakeAddToUserAgentFreeFormHandler(c.userAgent()))
+}
+
+func (c *ClusterConfig) newSTS() *sts.STS {
+	svc := sts.New(c.Session, c.stsConfig())
+	c.addUserAgent(&svc.Handlers)
+	return svc
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// Validate checks the config for mistakes without calling AWS and returns
+// all problems found. It is called by NewAuthClient.
+func (c *ClusterConfig) Validate() error {
+	var errs []error
+
+	if c.ClusterName == "" && c.ClusterARN == "" {
+		errs = append(errs, errors.New("ClusterName or ClusterARN must be set"))
+	} else if c.ClusterName == "" {
+		if _, err := parseClusterARN(c.ClusterARN); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Region != "" && !regionPattern.MatchString(c.Region) {
+		errs = append(errs, errors.Errorf("invalid Region %q", c.Region))
+	}
+	if c.STSRegion != "" && !regionPattern.MatchString(c.STSRegion) {
+		errs = append(errs, errors.Errorf("invalid STSRegion %q", c.STSRegion))
+	}
+
+	for _, roleARN := range c.assumeRoleChain() {
+		if err := validateRoleARN(roleARN); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := c.tokenCacheTTL(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(c.ImpersonateGroups) > 0 && c.ImpersonateUser == "" {
+		errs = append(errs, errors.New("ImpersonateGroups requires ImpersonateUser"))
+	}
+
+	if c.EndpointOverride != "" {
+		if u, err := url.Parse(c.EndpointOverride); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, errors.Errorf("invalid EndpointOverride %q: must be an https URL", c.EndpointOverride))
+		}
+	}
+
+	switch c.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		errs = append(errs, errors.Errorf("invalid LogFormat %q: must be %s or %s", c.LogFormat, LogFormatText, LogFormatJSON))
+	}
+
+	if c.ProxyURL != "" {
+		if u, err := url.Parse(c.ProxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.Errorf("invalid ProxyURL %q", c.ProxyURL))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// parseClusterARN returns the cluster name of an EKS cluster ARN.
+func parseClusterARN(clusterARN string) (string, error) {
+	parsed, err := arn.Parse(clusterARN)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid cluster ARN %q", clusterARN)
+	}
+	name := strings.TrimPrefix(parsed.Resource, "cluster/")
+	if parsed.Service != "eks" || name == parsed.Resource || name == "" || strings.Contains(name, "/") {
+		return "", errors.Errorf("invalid cluster ARN %q: expected arn:<partition>:eks:<region>:<account>:cluster/<name>", clusterARN)
+	}
+	return name, nil
+}
+
+func validateRoleARN(roleARN string) error {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return errors.Wrapf(err, "invalid role ARN %q", roleARN)
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return errors.Errorf("invalid role ARN %q: expected arn:<partition>:iam::<account>:role/<name>", roleARN)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClusterConfig
+		errs   int
+	}{
+		{
+			name:   "valid",
+			config: ClusterConfig{ClusterName: "test", Region: "us-west-2", AssumeRoleARN: "arn:aws:iam::111122223333:role/team/Admin"},
+		},
+		{
+			name:   "valid cluster ARN",
+			config: ClusterConfig{ClusterARN: "arn:aws:eks:us-west-2:111122223333:cluster/test"},
+		},
+		{name: "no cluster", config: ClusterConfig{}, errs: 1},
+		{name: "cluster ARN of another service", config: ClusterConfig{ClusterARN: "arn:aws:ec2:us-west-2:111122223333:instance/i-1"}, errs: 1},
+		{name: "invalid region", config: ClusterConfig{ClusterName: "test", Region: "us_west_2"}, errs: 1},
+		{name: "user ARN as role", config: ClusterConfig{ClusterName: "test", AssumeRoleARN: "arn:aws:iam::111122223333:user/tester"}, errs: 1},
+		{name: "groups without user", config: ClusterConfig{ClusterName: "test", ImpersonateGroups: []string{"admins"}}, errs: 1},
+		{name: "plain http endpoint", config: ClusterConfig{ClusterName: "test", EndpointOverride: "http://localhost:8443"}, errs: 1},
+		{
+			name: "several problems",
+			config: ClusterConfig{
+				Region:         "nowhere",
+				AssumeRoleARNs: []string{"not-an-arn"},
+				LogFormat:      "yaml",
+				ProxyURL:       "proxy:3128",
+			},
+			errs: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errs == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v", err)
+				}
+				return
+			}
+			agg, ok := err.(utilerrors.Aggregate)
+			if !ok {
+				t.Fatalf("Validate() = %v, want %d errors", err, tt.errs)
+			}
+			if len(agg.Errors()) != tt.errs {
+				t.Errorf("Validate() = %v, want %d errors", err, tt.errs)
+			}
+		})
+	}
+}
+
+func TestNewAuthClientValidates(t *testing.T) {
+	fake := newFakeAWS()
+	config := testConfig(t, fake, nil)
+	config.Region = "nowhere"
+
+	if _, err := NewAuthClient(config); err == nil {
+		t.Fatal("NewAuthClient() = nil error for an invalid region")
+	}
+	if ops := fake.operations(); len(ops) != 0 {
+		t.Errorf("AWS called for an invalid config: %q", ops)
+	}
+}
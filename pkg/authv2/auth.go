@@ -0,0 +1,239 @@
+// Package authv2 creates clientsets for EKS clusters like package auth, using
+// the AWS SDK for Go v2 instead of v1.
+package authv2
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// EKSAPI is the part of the EKS client used by this package.
+type EKSAPI interface {
+	DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error)
+}
+
+// STSAPI is the part of the STS client used by this package.
+type STSAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// ClusterConfig describes how to find and authenticate to an EKS cluster. It
+// must not be used by several goroutines at once.
+type ClusterConfig struct {
+	ClusterName string
+
+	// Region is the region of the cluster. The region from the environment
+	// or shared config is used when empty.
+	Region string
+
+	MasterEndpoint           string
+	CertificateAuthorityData string
+
+	// AWSConfig is used for all AWS calls. When nil it is loaded with
+	// config.LoadDefaultConfig.
+	AWSConfig *aws.Config
+
+	// EKS is the client used to look up the cluster, e.g. a fake in tests.
+	// Defaults to a client built from AWSConfig.
+	EKS EKSAPI
+
+	// STS is the client used for the caller identity check, e.g. a fake in
+	// tests. Defaults to a client built from AWSConfig. Tokens are always
+	// presigned with a client built from AWSConfig.
+	STS STSAPI
+}
+
+// NewAuthClient creates a new EKS authenticated clientset.
+func NewAuthClient(config *ClusterConfig) (clientset.Interface, error) {
+	return NewAuthClientWithContext(context.Background(), config)
+}
+
+// NewAuthClientWithContext creates a new EKS authenticated clientset. The
+// context bounds the AWS calls made to look up the cluster and identity.
+func NewAuthClientWithContext(ctx context.Context, config *ClusterConfig) (clientset.Interface, error) {
+	if err := config.ensureAWSConfig(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := config.loadConfig(ctx); err != nil {
+		return nil, errors.Wrap(err, "Unable to load Kubernetes Client Config")
+	}
+
+	client, err := config.NewClientConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Config")
+	}
+
+	clientset, err := client.NewClientSetWithEmbeddedToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create Kubernetes Client Set")
+	}
+	return clientset, nil
+}
+
+func (c *ClusterConfig) ensureAWSConfig(ctx context.Context) error {
+	if c.AWSConfig != nil {
+		return nil
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if c.Region != "" {
+		opts = append(opts, config.WithRegion(c.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return errors.Wrap(err, "loading AWS config")
+	}
+	c.AWSConfig = &cfg
+	return nil
+}
+
+// Retrieve EKS cluster endpoint and CA from AWS
+func (c *ClusterConfig) loadConfig(ctx context.Context) error {
+	if c.ClusterName == "" {
+		return errors.New("ClusterName cannot be empty")
+	}
+
+	svc := c.EKS
+	if svc == nil {
+		svc = eks.NewFromConfig(*c.AWSConfig)
+	}
+
+	log.WithField("cluster", c.ClusterName).Info("Looking up EKS cluster")
+
+	result, err := svc.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(c.ClusterName),
+	})
+	if err != nil {
+		log.WithField("cluster", c.ClusterName).Error(err)
+		return errors.Wrap(err, "describing cluster")
+	}
+
+	cluster := result.Cluster
+	if cluster == nil || cluster.Endpoint == nil || cluster.CertificateAuthority == nil || cluster.CertificateAuthority.Data == nil {
+		return errors.Errorf("cluster %s has no endpoint or certificate authority", c.ClusterName)
+	}
+	if cluster.Status != types.ClusterStatusActive {
+		return errors.Errorf("cluster %s has status %s, expected %s", c.ClusterName, cluster.Status, types.ClusterStatusActive)
+	}
+
+	log.WithField("cluster", c.ClusterName).Info("Found cluster")
+
+	c.MasterEndpoint = *cluster.Endpoint
+	c.CertificateAuthorityData = *cluster.CertificateAuthority.Data
+	return nil
+}
+
+// NewClientConfig builds the kubeconfig for the cluster. The endpoint and CA
+// must already be set on the config, e.g. by NewAuthClient.
+func (c *ClusterConfig) NewClientConfig(ctx context.Context) (*ClientConfig, error) {
+	if err := c.ensureAWSConfig(ctx); err != nil {
+		return nil, err
+	}
+
+	stsAPI := c.STS
+	if stsAPI == nil {
+		stsAPI = sts.NewFromConfig(*c.AWSConfig)
+	}
+	output, err := stsAPI.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, errors.Wrap(err, "checking AWS STS access – cannot get role ARN for current session")
+	}
+	iamRoleARN := aws.ToString(output.Arn)
+	log.Debugf("role ARN for the current session is %s", iamRoleARN)
+
+	ca, err := base64.StdEncoding.DecodeString(c.CertificateAuthorityData)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding certificate authority data")
+	}
+
+	contextName := fmt.Sprintf("%s@%s", getUsername(iamRoleARN), c.ClusterName)
+	return &ClientConfig{
+		Client: &clientcmdapi.Config{
+			Clusters: map[string]*clientcmdapi.Cluster{
+				c.ClusterName: {
+					Server:                   c.MasterEndpoint,
+					CertificateAuthorityData: ca,
+				},
+			},
+			Contexts: map[string]*clientcmdapi.Context{
+				contextName: {
+					Cluster:  c.ClusterName,
+					AuthInfo: contextName,
+				},
+			},
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{
+				contextName: {},
+			},
+			CurrentContext: contextName,
+		},
+		ClusterName: c.ClusterName,
+		ContextName: contextName,
+		presign:     sts.NewPresignClient(sts.NewFromConfig(*c.AWSConfig)),
+	}, nil
+}
+
+func getUsername(iamRoleARN string) string {
+	usernameParts := strings.Split(iamRoleARN, "/")
+	if len(usernameParts) > 1 {
+		return usernameParts[len(usernameParts)-1]
+	}
+	return "iam-root-account"
+}
+
+type ClientConfig struct {
+	Client      *clientcmdapi.Config
+	ClusterName string
+	ContextName string
+	presign     *sts.PresignClient
+}
+
+// WithEmbeddedToken returns a copy of the config with a freshly generated
+// token for the current context. The receiver is not modified.
+func (c *ClientConfig) WithEmbeddedToken(ctx context.Context) (*ClientConfig, error) {
+	tok, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfigCopy := *c
+	clientConfigCopy.Client = c.Client.DeepCopy()
+	if clientConfigCopy.Client.AuthInfos == nil {
+		clientConfigCopy.Client.AuthInfos = make(map[string]*clientcmdapi.AuthInfo)
+	}
+	clientConfigCopy.Client.AuthInfos[c.ContextName] = &clientcmdapi.AuthInfo{Token: tok.Token}
+	return &clientConfigCopy, nil
+}
+
+// NewClientSetWithEmbeddedToken creates a clientset authenticated with a
+// freshly generated token.
+func (c *ClientConfig) NewClientSetWithEmbeddedToken(ctx context.Context) (clientset.Interface, error) {
+	clientConfig, err := c.WithEmbeddedToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating Kubernetes client config with embedded token")
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*clientConfig.Client, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Kubernetes client config")
+	}
+
+	client, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Kubernetes client")
+	}
+	return client, nil
+}
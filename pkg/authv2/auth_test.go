@@ -0,0 +1,251 @@
+package authv2
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pkg/errors"
+)
+
+const (
+	testRegion      = "us-west-2"
+	testClusterName = "test"
+	testCallerARN   = "arn:aws:iam::111122223333:user/tester"
+)
+
+// fakeEKS serves DescribeCluster from memory.
+type fakeEKS struct {
+	clusters map[string]*types.Cluster
+	err      error
+	names    []string
+}
+
+func (f *fakeEKS) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	name := aws.ToString(params.Name)
+	f.names = append(f.names, name)
+	if f.err != nil {
+		return nil, f.err
+	}
+	c, ok := f.clusters[name]
+	if !ok {
+		return nil, errors.Errorf("cluster %s not found", name)
+	}
+	return &eks.DescribeClusterOutput{Cluster: c}, nil
+}
+
+// fakeSTS answers GetCallerIdentity with arn, or fails with err.
+type fakeSTS struct {
+	arn string
+	err error
+}
+
+func (f *fakeSTS) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(f.arn)}, nil
+}
+
+// fakeAPIServer is a Kubernetes API server answering /version. It records
+// the bearer tokens it receives.
+type fakeAPIServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	s := &fakeAPIServer{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeAPIServer) serve(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.tokens = append(s.tokens, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+	s.mu.Unlock()
+	if req.URL.Path != "/version" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"major": "1", "minor": "17", "gitVersion": "v1.17.17-eks"})
+}
+
+// requestTokens returns the bearer tokens of the requests received.
+func (s *fakeAPIServer) requestTokens() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.tokens...)
+}
+
+// caData returns the server certificate as EKS encodes the cluster CA.
+func (s *fakeAPIServer) caData() string {
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+}
+
+func activeCluster(endpoint, ca string) *types.Cluster {
+	return &types.Cluster{
+		Name:                 aws.String(testClusterName),
+		Endpoint:             aws.String(endpoint),
+		CertificateAuthority: &types.Certificate{Data: aws.String(ca)},
+		Status:               types.ClusterStatusActive,
+	}
+}
+
+// testAWSConfig returns an AWS config with static credentials, so that
+// tokens can be presigned without touching the environment.
+func testAWSConfig() *aws.Config {
+	return &aws.Config{
+		Region:      testRegion,
+		Credentials: credentials.NewStaticCredentialsProvider("AKIDTEST", "secret", ""),
+	}
+}
+
+// testConfig returns a config for a cluster served by api.
+func testConfig(api *fakeAPIServer) *ClusterConfig {
+	return &ClusterConfig{
+		ClusterName: testClusterName,
+		AWSConfig:   testAWSConfig(),
+		EKS:         &fakeEKS{clusters: map[string]*types.Cluster{testClusterName: activeCluster(api.URL, api.caData())}},
+		STS:         &fakeSTS{arn: testCallerARN},
+	}
+}
+
+func TestNewAuthClient(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := testConfig(api)
+
+	client, err := NewAuthClient(config)
+	if err != nil {
+		t.Fatalf("NewAuthClient() = %v", err)
+	}
+	if config.MasterEndpoint != api.URL {
+		t.Errorf("MasterEndpoint = %q, want %q", config.MasterEndpoint, api.URL)
+	}
+	if got := config.EKS.(*fakeEKS).names; len(got) != 1 || got[0] != testClusterName {
+		t.Errorf("DescribeCluster names = %v, want [%s]", got, testClusterName)
+	}
+
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		t.Fatalf("ServerVersion() = %v", err)
+	}
+	if version.GitVersion != "v1.17.17-eks" {
+		t.Errorf("GitVersion = %q, want v1.17.17-eks", version.GitVersion)
+	}
+	tokens := api.requestTokens()
+	if len(tokens) != 1 || !strings.HasPrefix(tokens[0], tokenPrefix) {
+		t.Errorf("request tokens = %q, want one with prefix %q", tokens, tokenPrefix)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	inactive := activeCluster("https://example.com", "Y2E=")
+	inactive.Status = types.ClusterStatusCreating
+	noCA := activeCluster("https://example.com", "Y2E=")
+	noCA.CertificateAuthority = nil
+
+	tests := []struct {
+		name    string
+		cluster string
+		eks     *fakeEKS
+		want    string
+	}{
+		{name: "empty name", eks: &fakeEKS{}, want: "ClusterName cannot be empty"},
+		{name: "describe error", cluster: testClusterName, eks: &fakeEKS{err: errors.New("denied")}, want: "describing cluster: denied"},
+		{name: "inactive", cluster: testClusterName, eks: &fakeEKS{clusters: map[string]*types.Cluster{testClusterName: inactive}}, want: "has status CREATING, expected ACTIVE"},
+		{name: "no CA", cluster: testClusterName, eks: &fakeEKS{clusters: map[string]*types.Cluster{testClusterName: noCA}}, want: "has no endpoint or certificate authority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClusterConfig{ClusterName: tt.cluster, AWSConfig: testAWSConfig(), EKS: tt.eks}
+			err := config.loadConfig(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadConfig() = %v, want error containing %q", err, tt.want)
+			}
+			if config.MasterEndpoint != "" {
+				t.Errorf("MasterEndpoint = %q after error, want empty", config.MasterEndpoint)
+			}
+		})
+	}
+}
+
+func TestNewClientConfig(t *testing.T) {
+	api := newFakeAPIServer(t)
+	config := testConfig(api)
+	config.MasterEndpoint = api.URL
+	config.CertificateAuthorityData = api.caData()
+
+	client, err := config.NewClientConfig(context.Background())
+	if err != nil {
+		t.Fatalf("NewClientConfig() = %v", err)
+	}
+	if want := "tester@" + testClusterName; client.ContextName != want || client.Client.CurrentContext != want {
+		t.Errorf("context = %q, current %q, want %q", client.ContextName, client.Client.CurrentContext, want)
+	}
+	cluster := client.Client.Clusters[testClusterName]
+	if cluster == nil || cluster.Server != api.URL {
+		t.Fatalf("cluster entry = %+v, want server %q", cluster, api.URL)
+	}
+	ca, _ := base64.StdEncoding.DecodeString(api.caData())
+	if string(cluster.CertificateAuthorityData) != string(ca) {
+		t.Error("cluster CA is not the decoded certificate authority data")
+	}
+}
+
+func TestNewClientConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sts  *fakeSTS
+		ca   string
+		want string
+	}{
+		{name: "sts error", sts: &fakeSTS{err: errors.New("expired")}, want: "cannot get role ARN for current session: expired"},
+		{name: "bad CA", sts: &fakeSTS{arn: testCallerARN}, ca: "not base64!", want: "decoding certificate authority data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClusterConfig{
+				ClusterName:              testClusterName,
+				AWSConfig:                testAWSConfig(),
+				STS:                      tt.sts,
+				CertificateAuthorityData: tt.ca,
+			}
+			_, err := config.NewClientConfig(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewClientConfig() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUsername(t *testing.T) {
+	tests := []struct {
+		arn, want string
+	}{
+		{"arn:aws:iam::111122223333:user/tester", "tester"},
+		{"arn:aws:sts::111122223333:assumed-role/Admin/session", "session"},
+		{"arn:aws:iam::111122223333:root", "iam-root-account"},
+		{"", "iam-root-account"},
+	}
+	for _, tt := range tests {
+		if got := getUsername(tt.arn); got != tt.want {
+			t.Errorf("getUsername(%q) = %q, want %q", tt.arn, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package authv2
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/pkg/errors"
+)
+
+const (
+	clusterIDHeader = "x-k8s-aws-id"
+	tokenPrefix     = "k8s-aws-v1."
+
+	// The presigned URL is valid for 15 minutes. Tokens are reported to
+	// expire a minute early to leave room for clock skew, like the tokens of
+	// aws-iam-authenticator.
+	presignExpiry = "60"
+	tokenLifetime = 14 * time.Minute
+)
+
+// Token is a bearer token for the cluster.
+type Token struct {
+	Token      string
+	Expiration time.Time
+}
+
+// Token generates a bearer token for the cluster, in the same format as
+// aws-iam-authenticator.
+func (c *ClientConfig) Token(ctx context.Context) (Token, error) {
+	expiration := time.Now().Add(tokenLifetime)
+
+	req, err := c.presign.PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}, func(o *sts.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(o *sts.Options) {
+			o.APIOptions = append(o.APIOptions,
+				smithyhttp.SetHeaderValue(clusterIDHeader, c.ClusterName),
+				smithyhttp.SetHeaderValue("X-Amz-Expires", presignExpiry),
+			)
+		})
+	})
+	if err != nil {
+		return Token{}, errors.Wrap(err, "presigning STS GetCallerIdentity")
+	}
+
+	return Token{
+		Token:      tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL)),
+		Expiration: expiration,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package authv2
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClientConfig(t *testing.T) *ClientConfig {
+	t.Helper()
+	config := &ClusterConfig{
+		ClusterName: testClusterName,
+		AWSConfig:   testAWSConfig(),
+		STS:         &fakeSTS{arn: testCallerARN},
+	}
+	client, err := config.NewClientConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestToken(t *testing.T) {
+	client := testClientConfig(t)
+
+	before := time.Now()
+	tok, err := client.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() = %v", err)
+	}
+	if !strings.HasPrefix(tok.Token, tokenPrefix) {
+		t.Fatalf("token %q does not start with %q", tok.Token, tokenPrefix)
+	}
+	if exp := tok.Expiration.Sub(before); exp < tokenLifetime || exp > tokenLifetime+time.Minute {
+		t.Errorf("token expires in %v, want about %v", exp, tokenLifetime)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(tok.Token, tokenPrefix))
+	if err != nil {
+		t.Fatalf("decoding token: %v", err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatalf("parsing presigned URL: %v", err)
+	}
+	if u.Host != "sts."+testRegion+".amazonaws.com" {
+		t.Errorf("host = %q, want the regional STS endpoint", u.Host)
+	}
+	q := u.Query()
+	if got := q.Get("Action"); got != "GetCallerIdentity" {
+		t.Errorf("Action = %q, want GetCallerIdentity", got)
+	}
+	if got := q.Get("X-Amz-Expires"); got != presignExpiry {
+		t.Errorf("X-Amz-Expires = %q, want %q", got, presignExpiry)
+	}
+	if got := q.Get("X-Amz-SignedHeaders"); !strings.Contains(got, clusterIDHeader) {
+		t.Errorf("X-Amz-SignedHeaders = %q, want it to include %s", got, clusterIDHeader)
+	}
+}
+
+func TestWithEmbeddedToken(t *testing.T) {
+	client := testClientConfig(t)
+
+	embedded, err := client.WithEmbeddedToken(context.Background())
+	if err != nil {
+		t.Fatalf("WithEmbeddedToken() = %v", err)
+	}
+	if tok := embedded.Client.AuthInfos[client.ContextName].Token; !strings.HasPrefix(tok, tokenPrefix) {
+		t.Errorf("embedded token = %q, want prefix %q", tok, tokenPrefix)
+	}
+	if tok := client.Client.AuthInfos[client.ContextName].Token; tok != "" {
+		t.Errorf("receiver token = %q, want it left empty", tok)
+	}
+}
@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+const defaultFieldManager = "eksutil"
+
+type ApplyOptions struct {
+	// RESTMapper maps object kinds to API resources. When nil a new
+	// discovery-backed mapper is created for every call; pass one from
+	// NewCachedRESTMapper to share discovery across calls.
+	RESTMapper meta.RESTMapper
+
+	// Namespace is used for namespaced objects that don't set one.
+	// Defaults to "default".
+	Namespace string
+
+	// FieldManager is the server-side apply field manager. Defaults to
+	// "eksutil".
+	FieldManager string
+
+	// Force takes ownership of fields managed by other field managers.
+	Force bool
+
+	// NoMatchTimeout bounds how long ApplyDirectory retries objects whose
+	// kind is not registered yet. Defaults to 1 minute.
+	NoMatchTimeout time.Duration
+}
+
+// NewCachedRESTMapper returns a RESTMapper that performs discovery lazily and
+// keeps the result in memory, so that many applies only hit discovery once.
+func NewCachedRESTMapper(restConfig *rest.Config) (meta.RESTMapper, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating discovery client")
+	}
+	return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)), nil
+}
+
+// Apply creates or updates obj using server-side apply.
+func Apply(ctx context.Context, restConfig *rest.Config, obj *unstructured.Unstructured, opts ApplyOptions) error {
+	a, err := newApplier(restConfig, opts)
+	if err != nil {
+		return err
+	}
+	return a.apply(ctx, obj)
+}
+
+type applier struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+	opts   ApplyOptions
+
+	// retryInterval is the delay between applies of an object whose kind is
+	// not registered yet.
+	retryInterval time.Duration
+}
+
+func newApplier(restConfig *rest.Config, opts ApplyOptions) (*applier, error) {
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating dynamic client")
+	}
+
+	mapper := opts.RESTMapper
+	if mapper == nil {
+		mapper, err = NewCachedRESTMapper(restConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newApplierFor(client, mapper, opts), nil
+}
+
+func newApplierFor(client dynamic.Interface, mapper meta.RESTMapper, opts ApplyOptions) *applier {
+	if opts.Namespace == "" {
+		opts.Namespace = metav1.NamespaceDefault
+	}
+	if opts.FieldManager == "" {
+		opts.FieldManager = defaultFieldManager
+	}
+	return &applier{client: client, mapper: mapper, opts: opts, retryInterval: noMatchRetryInterval}
+}
+
+func (a *applier) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk := obj.GroupVersionKind()
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return errors.Wrapf(err, "mapping %s", gvk)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s %q", gvk.Kind, obj.GetName())
+	}
+
+	var resource dynamic.ResourceInterface = a.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = a.opts.Namespace
+		}
+		resource = a.client.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	force := a.opts.Force
+	_, err = resource.Patch(obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: a.opts.FieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "applying %s %q", gvk.Kind, obj.GetName())
+	}
+
+	log.WithFields(log.Fields{
+		"kind": gvk.Kind,
+		"name": obj.GetName(),
+	}).Info("Applied object")
+	return nil
+}
@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// patchOptionsRecorder is a dynamic client recording the options of patches,
+// which the fake dynamic client drops.
+type patchOptionsRecorder struct {
+	dynamic.Interface
+	opts *[]metav1.PatchOptions
+}
+
+func (r patchOptionsRecorder) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return recordingResource{r.Interface.Resource(gvr), r.opts}
+}
+
+type recordingResource struct {
+	dynamic.NamespaceableResourceInterface
+	opts *[]metav1.PatchOptions
+}
+
+func (r recordingResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return recordingNamespacedResource{r.NamespaceableResourceInterface.Namespace(namespace), r.opts}
+}
+
+func (r recordingResource) Patch(name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	*r.opts = append(*r.opts, opts)
+	return r.NamespaceableResourceInterface.Patch(name, pt, data, opts, subresources...)
+}
+
+type recordingNamespacedResource struct {
+	dynamic.ResourceInterface
+	opts *[]metav1.PatchOptions
+}
+
+func (r recordingNamespacedResource) Patch(name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	*r.opts = append(*r.opts, opts)
+	return r.ResourceInterface.Patch(name, pt, data, opts, subresources...)
+}
+
+// recordPatchOptions makes f record the options of its patches.
+func recordPatchOptions(f *fakeApplier) *[]metav1.PatchOptions {
+	opts := &[]metav1.PatchOptions{}
+	f.client = patchOptionsRecorder{f.dynamic, opts}
+	return opts
+}
+
+func manifestObject(t *testing.T, manifest string) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    string
+		opts        ApplyOptions
+		wantApplied string
+		wantManager string
+		wantForce   bool
+	}{
+		{
+			name:        "namespace defaults",
+			manifest:    configMapManifest,
+			wantApplied: "configmaps/default/settings",
+			wantManager: "eksutil",
+		},
+		{
+			name:        "namespace option",
+			manifest:    configMapManifest,
+			opts:        ApplyOptions{Namespace: "team-a", FieldManager: "deployer", Force: true},
+			wantApplied: "configmaps/team-a/settings",
+			wantManager: "deployer",
+			wantForce:   true,
+		},
+		{
+			name:        "object namespace",
+			manifest:    deploymentManifest,
+			opts:        ApplyOptions{Namespace: "team-a"},
+			wantApplied: "deployments/apps/web",
+			wantManager: "eksutil",
+		},
+		{
+			name:        "cluster-scoped",
+			manifest:    namespaceManifest,
+			opts:        ApplyOptions{Namespace: "team-a"},
+			wantApplied: "namespaces//apps",
+			wantManager: "eksutil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeApplier(tt.opts)
+			opts := recordPatchOptions(f)
+			obj := manifestObject(t, tt.manifest)
+
+			if err := f.apply(context.Background(), obj); err != nil {
+				t.Fatalf("apply() = %v", err)
+			}
+
+			if len(f.applied) != 1 || f.applied[0] != tt.wantApplied {
+				t.Fatalf("applied %v, want %s", f.applied, tt.wantApplied)
+			}
+			patch := f.dynamic.Actions()[0].(k8stesting.PatchAction)
+			if patch.GetPatchType() != types.ApplyPatchType {
+				t.Errorf("patch type = %s, want %s", patch.GetPatchType(), types.ApplyPatchType)
+			}
+			var sent map[string]interface{}
+			if err := json.Unmarshal(patch.GetPatch(), &sent); err != nil {
+				t.Fatal(err)
+			}
+			if sent["kind"] != obj.GetKind() || sent["metadata"].(map[string]interface{})["name"] != obj.GetName() {
+				t.Errorf("patch = %s, want the object", patch.GetPatch())
+			}
+			if len(*opts) != 1 {
+				t.Fatalf("%d patches recorded, want 1", len(*opts))
+			}
+			got := (*opts)[0]
+			if got.FieldManager != tt.wantManager || got.Force == nil || *got.Force != tt.wantForce {
+				t.Errorf("patch options = %+v, want field manager %s and force %t", got, tt.wantManager, tt.wantForce)
+			}
+		})
+	}
+}
+
+func TestApplyNoMatch(t *testing.T) {
+	f := newFakeApplier(ApplyOptions{})
+
+	err := f.apply(context.Background(), manifestObject(t, widgetManifest))
+	if !meta.IsNoMatchError(errors.Cause(err)) || !strings.Contains(err.Error(), "mapping example.com/v1, Kind=Widget") {
+		t.Errorf("apply() = %v, want a no match error", err)
+	}
+	if len(f.dynamic.Actions()) != 0 {
+		t.Errorf("%d patches for an unknown kind, want 0", len(f.dynamic.Actions()))
+	}
+}
+
+func TestApplyPatchError(t *testing.T) {
+	f := newFakeApplier(ApplyOptions{})
+	f.dynamic.PrependReactor("patch", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("conflict")
+	})
+
+	err := f.apply(context.Background(), manifestObject(t, configMapManifest))
+	if err == nil || !strings.Contains(err.Error(), `applying ConfigMap "settings"`) {
+		t.Errorf("apply() = %v, want a patch error", err)
+	}
+}
+
+func TestApplyCancelled(t *testing.T) {
+	f := newFakeApplier(ApplyOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := f.apply(ctx, manifestObject(t, configMapManifest)); err != context.Canceled {
+		t.Errorf("apply() = %v, want %v", err, context.Canceled)
+	}
+	if len(f.applied) != 0 || f.discoveries() != 0 {
+		t.Errorf("applied %v after %d discoveries, want nothing", f.applied, f.discoveries())
+	}
+}
@@ -0,0 +1,142 @@
+package cluster
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	defaultNoMatchTimeout = time.Minute
+	noMatchRetryInterval  = 2 * time.Second
+)
+
+// ApplyDirectory server-side applies every object in the .yaml and .yml files
+// under dir. CustomResourceDefinitions are applied first, then Namespaces, then
+// everything else in file order. Objects whose kind is not known yet are
+// retried until their CRD is registered or opts.NoMatchTimeout elapses.
+func ApplyDirectory(ctx context.Context, restConfig *rest.Config, dir string, opts ApplyOptions) error {
+	a, err := newApplier(restConfig, opts)
+	if err != nil {
+		return err
+	}
+	return a.applyDirectory(ctx, dir)
+}
+
+func (a *applier) applyDirectory(ctx context.Context, dir string) error {
+	objects, err := readManifests(dir)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return applyOrder(objects[i]) < applyOrder(objects[j])
+	})
+
+	timeout := a.opts.NoMatchTimeout
+	if timeout <= 0 {
+		timeout = defaultNoMatchTimeout
+	}
+
+	for _, obj := range objects {
+		if err := a.applyWithNoMatchRetry(ctx, obj, timeout); err != nil {
+			return err
+		}
+	}
+	log.WithField("dir", dir).Infof("Applied %d objects", len(objects))
+	return nil
+}
+
+func (a *applier) applyWithNoMatchRetry(ctx context.Context, obj *unstructured.Unstructured, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := a.apply(ctx, obj)
+		if err == nil || !meta.IsNoMatchError(errors.Cause(err)) || time.Now().After(deadline) {
+			return err
+		}
+
+		log.WithField("kind", obj.GetKind()).Debug("Kind not registered yet, retrying")
+		if m, ok := a.mapper.(interface{ Reset() }); ok {
+			m.Reset()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a.retryInterval):
+		}
+	}
+}
+
+func applyOrder(obj *unstructured.Unstructured) int {
+	switch obj.GetKind() {
+	case "CustomResourceDefinition":
+		return 0
+	case "Namespace":
+		return 1
+	}
+	return 2
+}
+
+func readManifests(dir string) ([]*unstructured.Unstructured, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading manifests in %q", dir)
+	}
+	sort.Strings(files)
+
+	var objects []*unstructured.Unstructured
+	for _, file := range files {
+		objs, err := readManifestFile(file)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func readManifestFile(file string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q", file)
+	}
+	defer f.Close()
+
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				return objects, nil
+			}
+			return nil, errors.Wrapf(err, "parsing %q", file)
+		}
+		// Skip empty documents, e.g. a trailing "---".
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+}
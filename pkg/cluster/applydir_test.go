@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/restmapper"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var widgetResources = &metav1.APIResourceList{
+	GroupVersion: "example.com/v1",
+	APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+}
+
+// fakeApplier is an applier using fake dynamic and discovery clients. The
+// discovery client serves core, apps and apiextensions resources.
+type fakeApplier struct {
+	*applier
+	dynamic   *dynamicfake.FakeDynamicClient
+	discovery *fakediscovery.FakeDiscovery
+
+	// applied lists the server-side applies made, as resource/namespace/name.
+	applied []string
+}
+
+func newFakeApplier(opts ApplyOptions) *fakeApplier {
+	f := &fakeApplier{
+		dynamic: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		discovery: &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*metav1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace"},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			}},
+			{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			}},
+			{GroupVersion: "apiextensions.k8s.io/v1beta1", APIResources: []metav1.APIResource{
+				{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"},
+			}},
+		}}},
+	}
+
+	// The fake dynamic client does not support apply patches, so answer
+	// them with the applied object.
+	f.dynamic.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(patch.GetPatch(), &obj.Object); err != nil {
+			return true, nil, err
+		}
+		f.applied = append(f.applied, strings.Join([]string{patch.GetResource().Resource, patch.GetNamespace(), patch.GetName()}, "/"))
+		return true, obj, nil
+	})
+
+	f.applier = newApplierFor(f.dynamic, restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(f.discovery)), opts)
+	f.retryInterval = time.Millisecond
+	return f
+}
+
+// discoveries returns how often the API groups were discovered.
+func (f *fakeApplier) discoveries() int {
+	n := 0
+	for _, a := range f.discovery.Actions() {
+		if a.GetVerb() == "get" && a.GetResource().Resource == "group" {
+			n++
+		}
+	}
+	return n
+}
+
+func writeManifests(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const (
+	deploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+`
+	configMapManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+	namespaceManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+`
+	crdManifest = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`
+	widgetManifest = `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: gadget
+  namespace: apps
+`
+)
+
+func TestApplyDirectoryOrder(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"a.yaml":    deploymentManifest + "---\n" + configMapManifest + "---\n",
+		"b.yml":     namespaceManifest,
+		"c.YAML":    crdManifest,
+		"notes.txt": configMapManifest,
+	})
+	f := newFakeApplier(ApplyOptions{})
+
+	if err := f.applyDirectory(context.Background(), dir); err != nil {
+		t.Fatalf("applyDirectory() = %v", err)
+	}
+
+	want := []string{
+		"customresourcedefinitions//widgets.example.com",
+		"namespaces//apps",
+		"deployments/apps/web",
+		"configmaps/default/settings",
+	}
+	if strings.Join(f.applied, " ") != strings.Join(want, " ") {
+		t.Errorf("applied %v, want %v", f.applied, want)
+	}
+	for _, a := range f.dynamic.Actions() {
+		patch := a.(k8stesting.PatchAction)
+		if patch.GetPatchType() != "application/apply-patch+yaml" {
+			t.Errorf("%s patched with %s, want a server-side apply", patch.GetName(), patch.GetPatchType())
+		}
+	}
+}
+
+func TestApplyDirectoryRetriesNoMatch(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"crd.yaml":    crdManifest,
+		"widget.yaml": widgetManifest,
+	})
+	f := newFakeApplier(ApplyOptions{})
+
+	// Widgets are served once the CRD is applied, but discovery was cached
+	// before that.
+	f.dynamic.PrependReactor("patch", "customresourcedefinitions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		f.discovery.Resources = append(f.discovery.Resources, widgetResources)
+		return false, nil, nil
+	})
+
+	if err := f.applyDirectory(context.Background(), dir); err != nil {
+		t.Fatalf("applyDirectory() = %v", err)
+	}
+
+	want := []string{"customresourcedefinitions//widgets.example.com", "widgets/apps/gadget"}
+	if strings.Join(f.applied, " ") != strings.Join(want, " ") {
+		t.Errorf("applied %v, want %v", f.applied, want)
+	}
+	if n := f.discoveries(); n != 2 {
+		t.Errorf("%d discoveries, want one more after the kind had no match", n)
+	}
+}
+
+func TestApplyDirectoryNoMatchTimeout(t *testing.T) {
+	dir := writeManifests(t, map[string]string{"widget.yaml": widgetManifest})
+	f := newFakeApplier(ApplyOptions{NoMatchTimeout: 20 * time.Millisecond})
+
+	err := f.applyDirectory(context.Background(), dir)
+	if !meta.IsNoMatchError(errors.Cause(err)) {
+		t.Fatalf("applyDirectory() = %v, want a no match error", err)
+	}
+	if n := f.discoveries(); n < 2 {
+		t.Errorf("%d discoveries, want the kind looked up again before giving up", n)
+	}
+	if len(f.applied) != 0 {
+		t.Errorf("applied %v, want nothing", f.applied)
+	}
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	awsAuthNamespace = "kube-system"
+	awsAuthName      = "aws-auth"
+)
+
+type roleMapping struct {
+	RoleARN  string   `json:"rolearn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
+// IsRoleMapped reports whether roleARN is mapped in the aws-auth ConfigMap and
+// returns the Kubernetes groups it is mapped to. Both role and assumed-role
+// ARNs are accepted. ARNs are matched the way aws-iam-authenticator does:
+// ignoring case, with assumed-role ARNs turned into role ARNs without a path,
+// and mapRoles entries taken as written. An entry whose role has a path
+// therefore never matches an assumed-role ARN.
+func IsRoleMapped(ctx context.Context, cs kubernetes.Interface, roleARN string) (bool, []string, error) {
+	if err := ctx.Err(); err != nil {
+		return false, nil, err
+	}
+
+	want, err := canonicalRoleARN(roleARN)
+	if err != nil {
+		return false, nil, err
+	}
+
+	cm, err := cs.CoreV1().ConfigMaps(awsAuthNamespace).Get(awsAuthName, metav1.GetOptions{})
+	if err != nil {
+		return false, nil, errors.Wrap(err, "reading aws-auth ConfigMap")
+	}
+
+	var mappings []roleMapping
+	if err := yaml.Unmarshal([]byte(cm.Data["mapRoles"]), &mappings); err != nil {
+		return false, nil, errors.Wrap(err, "parsing mapRoles in aws-auth ConfigMap")
+	}
+
+	for _, m := range mappings {
+		if strings.ToLower(strings.TrimSpace(m.RoleARN)) == want {
+			return true, m.Groups, nil
+		}
+	}
+	return false, nil, nil
+}
+
+// canonicalRoleARN returns the lowercased ARN EKS matches against mapRoles:
+// role ARNs as they are and assumed-role ARNs converted to
+// arn:<partition>:iam::<account>:role/<name>, dropping the session name.
+func canonicalRoleARN(roleARN string) (string, error) {
+	roleARN = strings.TrimSpace(roleARN)
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing ARN %q", roleARN)
+	}
+
+	parts := strings.Split(parsed.Resource, "/")
+	switch {
+	case parsed.Service == "iam" && parts[0] == "role" && len(parts) >= 2:
+		return strings.ToLower(roleARN), nil
+	case parsed.Service == "sts" && parts[0] == "assumed-role" && len(parts) >= 2:
+		return strings.ToLower(arn.ARN{
+			Partition: parsed.Partition,
+			Service:   "iam",
+			AccountID: parsed.AccountID,
+			Resource:  "role/" + parts[1],
+		}.String()), nil
+	}
+	return "", errors.Errorf("%q is not an IAM role or assumed-role ARN", roleARN)
+}
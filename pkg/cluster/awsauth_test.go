@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testMapRoles = `
+- rolearn: arn:aws:iam::111122223333:role/team/Admin
+  username: admin
+  groups: [system:masters]
+- rolearn: arn:aws:iam::111122223333:role/DevOps
+  username: devops
+  groups: [devops, viewers]
+- rolearn: arn:aws:iam::111122223333:role/Deployer
+  username: deployer
+  groups: [deployers]
+`
+
+func TestIsRoleMapped(t *testing.T) {
+	cs := fake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: awsAuthNamespace, Name: awsAuthName},
+		Data:       map[string]string{"mapRoles": testMapRoles},
+	})
+
+	tests := []struct {
+		roleARN    string
+		wantMapped bool
+		wantGroups []string
+	}{
+		{"arn:aws:iam::111122223333:role/team/Admin", true, []string{"system:masters"}},
+		// EKS drops the path of assumed roles, so they never match an entry
+		// with one.
+		{"arn:aws:sts::111122223333:assumed-role/Admin/session", false, nil},
+		{"arn:aws:iam::111122223333:role/devops", true, []string{"devops", "viewers"}},
+		{"arn:aws:sts::111122223333:assumed-role/Deployer/ci-1234", true, []string{"deployers"}},
+		{"arn:aws:iam::444455556666:role/Deployer", false, nil},
+		{"arn:aws:iam::111122223333:role/Unknown", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.roleARN, func(t *testing.T) {
+			mapped, groups, err := IsRoleMapped(context.Background(), cs, tt.roleARN)
+			if err != nil {
+				t.Fatalf("IsRoleMapped() = %v", err)
+			}
+			if mapped != tt.wantMapped || !reflect.DeepEqual(groups, tt.wantGroups) {
+				t.Errorf("IsRoleMapped() = %v, %v, want %v, %v", mapped, groups, tt.wantMapped, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestIsRoleMappedInvalidARN(t *testing.T) {
+	for _, roleARN := range []string{"Admin", "arn:aws:iam::111122223333:user/alice"} {
+		if _, _, err := IsRoleMapped(context.Background(), fake.NewSimpleClientset(), roleARN); err == nil {
+			t.Errorf("IsRoleMapped(%q) = nil error, want an error", roleARN)
+		}
+	}
+}
+
+func TestIsRoleMappedMissingConfigMap(t *testing.T) {
+	_, _, err := IsRoleMapped(context.Background(), fake.NewSimpleClientset(), "arn:aws:iam::111122223333:role/DevOps")
+	if err == nil {
+		t.Fatal("IsRoleMapped() = nil error without aws-auth, want an error")
+	}
+}
@@ -0,0 +1,27 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetCoreDNSConfig returns the Corefile from the coredns ConfigMap in kube-system.
+func GetCoreDNSConfig(ctx context.Context, cs kubernetes.Interface) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	cm, err := cs.CoreV1().ConfigMaps("kube-system").Get("coredns", metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "reading coredns ConfigMap")
+	}
+
+	corefile, ok := cm.Data["Corefile"]
+	if !ok {
+		return "", errors.New("coredns ConfigMap has no Corefile")
+	}
+	return corefile, nil
+}
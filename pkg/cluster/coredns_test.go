@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testCorefile = `.:53 {
+    errors
+    health
+    kubernetes cluster.local in-addr.arpa ip6.arpa
+    forward . /etc/resolv.conf
+}
+`
+
+func coreDNSConfigMap(namespace string, data map[string]string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "coredns"},
+		Data:       data,
+	}
+}
+
+func TestGetCoreDNSConfig(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		coreDNSConfigMap("default", map[string]string{"Corefile": "not this one"}),
+		coreDNSConfigMap("kube-system", map[string]string{"Corefile": testCorefile}),
+	)
+
+	corefile, err := GetCoreDNSConfig(context.Background(), cs)
+	if err != nil {
+		t.Fatalf("GetCoreDNSConfig() = %v", err)
+	}
+	if corefile != testCorefile {
+		t.Errorf("GetCoreDNSConfig() = %q, want the kube-system Corefile", corefile)
+	}
+}
+
+func TestGetCoreDNSConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []*apiv1.ConfigMap
+		want    string
+	}{
+		{name: "no ConfigMap", want: "reading coredns ConfigMap"},
+		{
+			name:    "no Corefile",
+			objects: []*apiv1.ConfigMap{coreDNSConfigMap("kube-system", map[string]string{"Corefile.bak": testCorefile})},
+			want:    "coredns ConfigMap has no Corefile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			for _, cm := range tt.objects {
+				if err := cs.Tracker().Add(cm); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := GetCoreDNSConfig(context.Background(), cs)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("GetCoreDNSConfig() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Ask the API server to return only object metadata rather than full objects.
+const metadataAccept = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json"
+
+const countPageSize = 500
+
+type countedResource struct {
+	kind     string
+	resource string
+	client   func(kubernetes.Interface) rest.Interface
+}
+
+var countedResources = []countedResource{
+	{"Namespace", "namespaces", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"Node", "nodes", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"Pod", "pods", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"Service", "services", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"ConfigMap", "configmaps", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"Secret", "secrets", func(cs kubernetes.Interface) rest.Interface { return cs.CoreV1().RESTClient() }},
+	{"Deployment", "deployments", func(cs kubernetes.Interface) rest.Interface { return cs.AppsV1().RESTClient() }},
+	{"StatefulSet", "statefulsets", func(cs kubernetes.Interface) rest.Interface { return cs.AppsV1().RESTClient() }},
+	{"DaemonSet", "daemonsets", func(cs kubernetes.Interface) rest.Interface { return cs.AppsV1().RESTClient() }},
+	{"Job", "jobs", func(cs kubernetes.Interface) rest.Interface { return cs.BatchV1().RESTClient() }},
+}
+
+// ResourceCounts returns the number of objects of common kinds across all namespaces.
+// Only object metadata is requested from the API server to keep memory usage low.
+func ResourceCounts(ctx context.Context, cs kubernetes.Interface) (map[string]int, error) {
+	counts := make(map[string]int, len(countedResources))
+	for _, r := range countedResources {
+		n, err := countResource(ctx, r.client(cs), r.resource)
+		if err != nil {
+			return nil, errors.Wrapf(err, "counting %s", r.resource)
+		}
+		log.WithField("resource", r.resource).Debugf("Counted %d objects", n)
+		counts[r.kind] = n
+	}
+	return counts, nil
+}
+
+func countResource(ctx context.Context, client rest.Interface, resource string) (int, error) {
+	count := 0
+	opts := metav1.ListOptions{Limit: countPageSize}
+	for {
+		raw, err := client.Get().
+			Context(ctx).
+			SetHeader("Accept", metadataAccept).
+			Resource(resource).
+			VersionedParams(&opts, metav1.ParameterCodec).
+			Do().
+			Raw()
+		if err != nil {
+			return 0, err
+		}
+
+		var list metav1.PartialObjectMetadataList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return 0, errors.Wrap(err, "decoding metadata list")
+		}
+		count += len(list.Items)
+
+		if list.Continue == "" {
+			return count, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
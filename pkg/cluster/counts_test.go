@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// metadataServer is an API server answering metadata-only lists with
+// pages of at most two objects. Every resource holds one object, except
+// those in sizes.
+type metadataServer struct {
+	*httptest.Server
+
+	sizes  map[string]int
+	denied string
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newMetadataServer(t *testing.T, sizes map[string]int) *metadataServer {
+	s := &metadataServer{sizes: sizes}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *metadataServer) serve(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	resource := path.Base(req.URL.Path)
+	if resource == s.denied {
+		http.Error(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`, http.StatusForbidden)
+		return
+	}
+	size, ok := s.sizes[resource]
+	if !ok {
+		size = 1
+	}
+	start, _ := strconv.Atoi(req.URL.Query().Get("continue"))
+	end := start + 2
+	list := metav1.PartialObjectMetadataList{TypeMeta: metav1.TypeMeta{Kind: "PartialObjectMetadataList", APIVersion: "meta.k8s.io/v1"}}
+	for i := start; i < end && i < size; i++ {
+		list.Items = append(list.Items, metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", resource, i)}})
+	}
+	if end < size {
+		list.Continue = strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// requestsFor returns the requests made for resource.
+func (s *metadataServer) requestsFor(resource string) []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reqs []*http.Request
+	for _, req := range s.requests {
+		if path.Base(req.URL.Path) == resource {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+func (s *metadataServer) clientset(t *testing.T) kubernetes.Interface {
+	t.Helper()
+	cs, err := kubernetes.NewForConfig(&rest.Config{Host: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func TestResourceCounts(t *testing.T) {
+	s := newMetadataServer(t, map[string]int{"pods": 5, "secrets": 0, "deployments": 2})
+
+	counts, err := ResourceCounts(context.Background(), s.clientset(t))
+	if err != nil {
+		t.Fatalf("ResourceCounts() = %v", err)
+	}
+	want := map[string]int{
+		"Namespace": 1, "Node": 1, "Pod": 5, "Service": 1, "ConfigMap": 1, "Secret": 0,
+		"Deployment": 2, "StatefulSet": 1, "DaemonSet": 1, "Job": 1,
+	}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("ResourceCounts() = %v, want %v", counts, want)
+	}
+
+	pods := s.requestsFor("pods")
+	var continues []string
+	for _, req := range pods {
+		if req.URL.Path != "/api/v1/pods" {
+			t.Errorf("pods listed at %s, want all namespaces", req.URL.Path)
+		}
+		if got := req.Header.Get("Accept"); got != metadataAccept {
+			t.Errorf("Accept = %q, want %q", got, metadataAccept)
+		}
+		if got := req.URL.Query().Get("limit"); got != strconv.Itoa(countPageSize) {
+			t.Errorf("limit = %q, want %d", got, countPageSize)
+		}
+		continues = append(continues, req.URL.Query().Get("continue"))
+	}
+	if strings.Join(continues, ",") != ",2,4" {
+		t.Errorf("pods listed with continue tokens %q, want three pages", continues)
+	}
+	if reqs := s.requestsFor("jobs"); len(reqs) != 1 || reqs[0].URL.Path != "/apis/batch/v1/jobs" {
+		t.Errorf("jobs requests = %d, want one to the batch API", len(reqs))
+	}
+}
+
+func TestResourceCountsError(t *testing.T) {
+	s := newMetadataServer(t, nil)
+	s.denied = "secrets"
+
+	_, err := ResourceCounts(context.Background(), s.clientset(t))
+	if err == nil || !strings.Contains(err.Error(), "counting secrets") {
+		t.Errorf("ResourceCounts() = %v, want an error counting secrets", err)
+	}
+	if n := len(s.requestsFor("deployments")); n != 0 {
+		t.Errorf("%d deployments requests after the error, want 0", n)
+	}
+}
@@ -0,0 +1,176 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	defaultDrainTimeout      = 5 * time.Minute
+	defaultEvictionRetryWait = 5 * time.Second
+	mirrorPodAnnotation      = "kubernetes.io/config.mirror"
+)
+
+type DrainOptions struct {
+	// GracePeriod overrides the termination grace period of evicted pods.
+	// The pod's own grace period is used when zero.
+	GracePeriod time.Duration
+
+	// Timeout bounds how long draining a single node may take. Defaults to
+	// 5 minutes.
+	Timeout time.Duration
+
+	// EvictionRetryWait is how long to wait before retrying an eviction
+	// refused by a PodDisruptionBudget. Defaults to 5 seconds.
+	EvictionRetryWait time.Duration
+
+	// Concurrency is the number of nodes drained at the same time when
+	// draining a node group. Defaults to 1.
+	Concurrency int
+}
+
+func (o DrainOptions) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return defaultDrainTimeout
+}
+
+func (o DrainOptions) evictionRetryWait() time.Duration {
+	if o.EvictionRetryWait > 0 {
+		return o.EvictionRetryWait
+	}
+	return defaultEvictionRetryWait
+}
+
+// CordonNode marks the node as unschedulable.
+func CordonNode(ctx context.Context, cs kubernetes.Interface, nodeName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	if _, err := cs.CoreV1().Nodes().Patch(nodeName, types.StrategicMergePatchType, patch); err != nil {
+		return errors.Wrapf(err, "cordoning node %q", nodeName)
+	}
+	log.WithField("node", nodeName).Info("Cordoned node")
+	return nil
+}
+
+// DrainNode cordons the node and evicts all pods running on it except
+// DaemonSet and mirror pods. Evictions refused by a PodDisruptionBudget are
+// retried until the drain times out.
+func DrainNode(ctx context.Context, cs kubernetes.Interface, nodeName string, opts DrainOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	if err := CordonNode(ctx, cs, nodeName); err != nil {
+		return err
+	}
+
+	pods, err := cs.CoreV1().Pods("").List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "listing pods on node %q", nodeName)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !evictable(pod) {
+			continue
+		}
+		if err := evictPod(ctx, cs, pod, opts); err != nil {
+			return errors.Wrapf(err, "draining node %q", nodeName)
+		}
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !evictable(pod) {
+			continue
+		}
+		if err := waitForPodDeleted(ctx, cs, pod); err != nil {
+			return errors.Wrapf(err, "draining node %q", nodeName)
+		}
+	}
+
+	log.WithField("node", nodeName).Info("Drained node")
+	return nil
+}
+
+func evictable(pod *apiv1.Pod) bool {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	if pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed {
+		return false
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+func evictPod(ctx context.Context, cs kubernetes.Interface, pod *apiv1.Pod, opts DrainOptions) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+	if opts.GracePeriod > 0 {
+		seconds := int64(opts.GracePeriod / time.Second)
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &seconds}
+	}
+
+	for {
+		err := cs.CoreV1().Pods(pod.Namespace).Evict(eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			log.WithField("pod", pod.Namespace+"/"+pod.Name).Debug("Evicted pod")
+			return nil
+		case apierrors.IsTooManyRequests(err):
+			// Refused by a PodDisruptionBudget, try again later.
+			log.WithField("pod", pod.Namespace+"/"+pod.Name).Debug("Eviction blocked by disruption budget")
+		default:
+			return errors.Wrapf(err, "evicting pod %s/%s", pod.Namespace, pod.Name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "evicting pod %s/%s", pod.Namespace, pod.Name)
+		case <-time.After(opts.evictionRetryWait()):
+		}
+	}
+}
+
+func waitForPodDeleted(ctx context.Context, cs kubernetes.Interface, pod *apiv1.Pod) error {
+	for {
+		p, err := cs.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "getting pod %s/%s", pod.Namespace, pod.Name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "waiting for pod %s/%s to terminate", pod.Namespace, pod.Name)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testNode(name, nodegroup string) *apiv1.Node {
+	node := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if nodegroup != "" {
+		node.Labels = map[string]string{nodegroupLabel: nodegroup}
+	}
+	return node
+}
+
+func nodePod(name, nodeName string) *apiv1.Pod {
+	pod := testPod(name, true)
+	pod.UID = types.UID("uid-" + name)
+	pod.Spec.NodeName = nodeName
+	return pod
+}
+
+func daemonSetPod(name, nodeName string) *apiv1.Pod {
+	pod := nodePod(name, nodeName)
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "ds"}}
+	return pod
+}
+
+func mirrorPod(name, nodeName string) *apiv1.Pod {
+	pod := nodePod(name, nodeName)
+	pod.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	return pod
+}
+
+// fakeEvictions makes evictions delete the pod, after refusing the first
+// refusals evictions of each pod with 429 as a PodDisruptionBudget does. It
+// also makes pod lists honor the spec.nodeName field selector, which the
+// fake clientset ignores. It returns the names of the evicted pods.
+func fakeEvictions(cs *fake.Clientset, refusals int) *[]string {
+	var evicted []string
+	refused := make(map[string]int)
+	gvr := apiv1.SchemeGroupVersion.WithResource("pods")
+
+	cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := create.GetObject().(*policyv1beta1.Eviction)
+		if refused[eviction.Name] < refusals {
+			refused[eviction.Name]++
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		if err := cs.Tracker().Delete(gvr, eviction.Namespace, eviction.Name); err != nil {
+			return true, nil, err
+		}
+		evicted = append(evicted, eviction.Name)
+		return true, nil, nil
+	})
+
+	cs.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list := action.(k8stesting.ListAction)
+		nodeName, ok := list.GetListRestrictions().Fields.RequiresExactMatch("spec.nodeName")
+		if !ok {
+			return false, nil, nil
+		}
+		obj, err := cs.Tracker().List(gvr, apiv1.SchemeGroupVersion.WithKind("Pod"), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		pods := obj.(*apiv1.PodList)
+		var items []apiv1.Pod
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName == nodeName {
+				items = append(items, pod)
+			}
+		}
+		pods.Items = items
+		return true, pods, nil
+	})
+	return &evicted
+}
+
+func assertCordoned(t *testing.T, cs *fake.Clientset, nodeName string, want bool) {
+	t.Helper()
+	node, err := cs.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Unschedulable != want {
+		t.Errorf("node %s unschedulable = %v, want %v", nodeName, node.Spec.Unschedulable, want)
+	}
+}
+
+func assertEvicted(t *testing.T, evicted []string, want ...string) {
+	t.Helper()
+	got := append([]string(nil), evicted...)
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("evicted pods %v, want %v", got, want)
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		testNode("node-1", ""),
+		nodePod("web", "node-1"),
+		daemonSetPod("logs", "node-1"),
+		mirrorPod("kube-proxy", "node-1"),
+		nodePod("other", "node-2"),
+	)
+	evicted := fakeEvictions(cs, 0)
+
+	if err := DrainNode(context.Background(), cs, "node-1", DrainOptions{}); err != nil {
+		t.Fatalf("DrainNode() = %v", err)
+	}
+
+	assertCordoned(t, cs, "node-1", true)
+	assertEvicted(t, *evicted, "web")
+	for _, name := range []string{"logs", "kube-proxy", "other"} {
+		if _, err := cs.CoreV1().Pods("default").Get(name, metav1.GetOptions{}); err != nil {
+			t.Errorf("pod %s: %v, want it left running", name, err)
+		}
+	}
+}
+
+func TestDrainNodeRetriesEviction(t *testing.T) {
+	cs := fake.NewSimpleClientset(testNode("node-1", ""), nodePod("web", "node-1"))
+	evicted := fakeEvictions(cs, 2)
+
+	opts := DrainOptions{EvictionRetryWait: time.Millisecond}
+	if err := DrainNode(context.Background(), cs, "node-1", opts); err != nil {
+		t.Fatalf("DrainNode() = %v", err)
+	}
+
+	assertEvicted(t, *evicted, "web")
+	if n := countActions(cs, "create", "pods"); n != 3 {
+		t.Errorf("%d evictions, want 2 refused and 1 accepted", n)
+	}
+}
+
+func TestDrainNodeEvictionTimeout(t *testing.T) {
+	cs := fake.NewSimpleClientset(testNode("node-1", ""), nodePod("web", "node-1"))
+	fakeEvictions(cs, 1<<30)
+
+	opts := DrainOptions{Timeout: 50 * time.Millisecond, EvictionRetryWait: time.Millisecond}
+	err := DrainNode(context.Background(), cs, "node-1", opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DrainNode() = %v, want the drain timeout", err)
+	}
+}
+
+func TestDrainNodeEvictionError(t *testing.T) {
+	cs := fake.NewSimpleClientset(testNode("node-1", ""), nodePod("web", "node-1"))
+	cs.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(apiv1.Resource("pods"), "web", errors.New("denied"))
+	})
+
+	err := DrainNode(context.Background(), cs, "node-1", DrainOptions{})
+	if !apierrors.IsForbidden(errors.Cause(err)) {
+		t.Fatalf("DrainNode() = %v, want the eviction error", err)
+	}
+	if n := countActions(cs, "create", "pods"); n != 1 {
+		t.Errorf("%d evictions, want the failed eviction not retried", n)
+	}
+}
+
+// fakeEKSSession returns a session for an EKS API answering DescribeNodegroup
+// with status, and the paths requested.
+func fakeEKSSession(t *testing.T, status int) (*session.Session, *[]string) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.Header().Set("X-Amzn-Errortype", "ResourceNotFoundException")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"message":"No node group found"}`))
+			return
+		}
+		w.Write([]byte(`{"nodegroup":{"nodegroupName":"workers","status":"ACTIVE"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-west-2").
+		WithEndpoint(srv.URL).
+		WithCredentials(credentials.NewStaticCredentials("AKID", "SECRET", "")).
+		WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess, &paths
+}
+
+func TestDrainNodegroup(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		testNode("node-1", "workers"),
+		testNode("node-2", "workers"),
+		testNode("node-3", "other"),
+		nodePod("web-1", "node-1"),
+		nodePod("web-2", "node-2"),
+		daemonSetPod("logs", "node-2"),
+		nodePod("db", "node-3"),
+	)
+	evicted := fakeEvictions(cs, 0)
+	sess, paths := fakeEKSSession(t, http.StatusOK)
+
+	opts := DrainOptions{Concurrency: 2}
+	if err := DrainNodegroup(context.Background(), cs, sess, "test", "workers", opts); err != nil {
+		t.Fatalf("DrainNodegroup() = %v", err)
+	}
+
+	if len(*paths) != 1 || (*paths)[0] != "/clusters/test/node-groups/workers" {
+		t.Errorf("EKS requests %v, want one DescribeNodegroup", *paths)
+	}
+	assertCordoned(t, cs, "node-1", true)
+	assertCordoned(t, cs, "node-2", true)
+	assertCordoned(t, cs, "node-3", false)
+	assertEvicted(t, *evicted, "web-1", "web-2")
+}
+
+func TestDrainNodegroupNotFound(t *testing.T) {
+	cs := fake.NewSimpleClientset(testNode("node-1", "workers"), nodePod("web", "node-1"))
+	evicted := fakeEvictions(cs, 0)
+	sess, _ := fakeEKSSession(t, http.StatusNotFound)
+
+	if err := DrainNodegroup(context.Background(), cs, sess, "test", "workers", DrainOptions{}); err == nil {
+		t.Fatal("DrainNodegroup() = nil, want the DescribeNodegroup error")
+	}
+	assertCordoned(t, cs, "node-1", false)
+	assertEvicted(t, *evicted)
+}
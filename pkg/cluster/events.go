@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EventFilter selects which events are passed to the handler. Empty fields
+// match everything.
+type EventFilter struct {
+	Type       string
+	Reason     string
+	ObjectKind string
+}
+
+type EventInfo struct {
+	Namespace  string
+	Name       string
+	Type       string
+	Reason     string
+	Message    string
+	ObjectKind string
+	ObjectName string
+	Count      int32
+	LastSeen   time.Time
+}
+
+// WatchEvents watches events in all namespaces and invokes handler for every
+// event matching filter until ctx is cancelled. The watch is re-established
+// when the server closes it or the resource version expires, backing off while
+// watches keep ending right after they start.
+func WatchEvents(ctx context.Context, cs kubernetes.Interface, filter EventFilter, handler func(EventInfo)) error {
+	events := cs.CoreV1().Events("")
+	selector := filter.fieldSelector()
+	resourceVersion := ""
+
+	var backoff rewatchBackoff
+	for {
+		if resourceVersion == "" {
+			opts, err := withContext(ctx, metav1.ListOptions{FieldSelector: selector, Limit: 1})
+			if err != nil {
+				return nil
+			}
+			list, err := events.List(opts)
+			if err != nil {
+				return errors.Wrap(err, "listing events")
+			}
+			resourceVersion = list.ResourceVersion
+		}
+
+		opts, err := withContext(ctx, metav1.ListOptions{FieldSelector: selector, ResourceVersion: resourceVersion})
+		if err != nil {
+			return nil
+		}
+		w, err := events.Watch(opts)
+		if err != nil {
+			return errors.Wrap(err, "watching events")
+		}
+
+		backoff.started()
+		resourceVersion, err = consumeEvents(ctx, w, resourceVersion, filter, handler)
+		w.Stop()
+		if err != nil {
+			return err
+		}
+		if backoff.wait(ctx) != nil {
+			return nil
+		}
+		log.WithField("resourceVersion", resourceVersion).Debug("Re-establishing event watch")
+	}
+}
+
+// consumeEvents reads from w until it closes and returns the resource version
+// to resume from, or an empty string if the watch must start over.
+func consumeEvents(ctx context.Context, w watch.Interface, resourceVersion string, filter EventFilter, handler func(EventInfo)) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, nil
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, nil
+			}
+			switch e.Type {
+			case watch.Error:
+				status := apierrors.FromObject(e.Object)
+				if apierrors.IsResourceExpired(status) || apierrors.IsGone(status) {
+					return "", nil
+				}
+				return resourceVersion, errors.Wrap(status, "event watch failed")
+			case watch.Added, watch.Modified:
+				ev, ok := e.Object.(*apiv1.Event)
+				if !ok {
+					continue
+				}
+				resourceVersion = ev.ResourceVersion
+				if filter.matches(ev) {
+					handler(newEventInfo(ev))
+				}
+			}
+		}
+	}
+}
+
+func (f EventFilter) fieldSelector() string {
+	set := fields.Set{}
+	if f.Type != "" {
+		set["type"] = f.Type
+	}
+	if f.Reason != "" {
+		set["reason"] = f.Reason
+	}
+	if f.ObjectKind != "" {
+		set["involvedObject.kind"] = f.ObjectKind
+	}
+	return fields.SelectorFromSet(set).String()
+}
+
+func (f EventFilter) matches(ev *apiv1.Event) bool {
+	if f.Type != "" && f.Type != ev.Type {
+		return false
+	}
+	if f.Reason != "" && f.Reason != ev.Reason {
+		return false
+	}
+	if f.ObjectKind != "" && f.ObjectKind != ev.InvolvedObject.Kind {
+		return false
+	}
+	return true
+}
+
+func newEventInfo(ev *apiv1.Event) EventInfo {
+	lastSeen := ev.LastTimestamp.Time
+	if lastSeen.IsZero() {
+		lastSeen = ev.EventTime.Time
+	}
+	return EventInfo{
+		Namespace:  ev.Namespace,
+		Name:       ev.Name,
+		Type:       ev.Type,
+		Reason:     ev.Reason,
+		Message:    ev.Message,
+		ObjectKind: ev.InvolvedObject.Kind,
+		ObjectName: ev.InvolvedObject.Name,
+		Count:      ev.Count,
+		LastSeen:   lastSeen,
+	}
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testEvent(name, eventType, reason, kind, resourceVersion string) *apiv1.Event {
+	return &apiv1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: name, ResourceVersion: resourceVersion},
+		Type:           eventType,
+		Reason:         reason,
+		InvolvedObject: apiv1.ObjectReference{Kind: kind, Name: "web"},
+		Count:          1,
+	}
+}
+
+// fakeEventWatches makes the clientset hand out fake event watchers that the
+// test feeds with events.
+func fakeEventWatches(cs *fake.Clientset) chan *watch.FakeWatcher {
+	watches := make(chan *watch.FakeWatcher, 10)
+	cs.PrependWatchReactor("events", func(k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(10, false)
+		watches <- w
+		return true, w, nil
+	})
+	return watches
+}
+
+// watchEventsAsync runs WatchEvents until cancel is called, passing the events
+// handled to the returned channel.
+func watchEventsAsync(cs *fake.Clientset, filter EventFilter) (chan EventInfo, chan error, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan EventInfo, 10)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- WatchEvents(ctx, cs, filter, func(ev EventInfo) { events <- ev })
+	}()
+	return events, errc, cancel
+}
+
+func watchRestrictions(cs *fake.Clientset) []k8stesting.WatchRestrictions {
+	var restrictions []k8stesting.WatchRestrictions
+	for _, a := range cs.Actions() {
+		if w, ok := a.(k8stesting.WatchAction); ok && a.GetResource().Resource == "events" {
+			restrictions = append(restrictions, w.GetWatchRestrictions())
+		}
+	}
+	return restrictions
+}
+
+func TestWatchEventsFilter(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	watches := fakeEventWatches(cs)
+	filter := EventFilter{Type: apiv1.EventTypeWarning, Reason: "BackOff", ObjectKind: "Pod"}
+	events, errc, cancel := watchEventsAsync(cs, filter)
+
+	// The fake clientset ignores field selectors, so every event reaches
+	// the client-side filter.
+	w := <-watches
+	w.Add(testEvent("normal", apiv1.EventTypeNormal, "BackOff", "Pod", "2"))
+	w.Add(testEvent("mount", apiv1.EventTypeWarning, "FailedMount", "Pod", "3"))
+	w.Add(testEvent("node", apiv1.EventTypeWarning, "BackOff", "Node", "4"))
+	w.Modify(testEvent("backoff", apiv1.EventTypeWarning, "BackOff", "Pod", "5"))
+
+	ev := <-events
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("WatchEvents() = %v", err)
+	}
+	if ev.Name != "backoff" || ev.ObjectKind != "Pod" || ev.ObjectName != "web" || ev.Reason != "BackOff" {
+		t.Errorf("handled %+v, want the BackOff warning of the pod", ev)
+	}
+	if len(events) != 0 {
+		t.Errorf("%d more events handled, want only the matching one", len(events))
+	}
+
+	restrictions := watchRestrictions(cs)
+	if len(restrictions) != 1 {
+		t.Fatalf("%d watches, want 1", len(restrictions))
+	}
+	for field, want := range map[string]string{"type": "Warning", "reason": "BackOff", "involvedObject.kind": "Pod"} {
+		if got, ok := restrictions[0].Fields.RequiresExactMatch(field); !ok || got != want {
+			t.Errorf("watch selector %s = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestWatchEventsRewatches(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	watches := fakeEventWatches(cs)
+	events, errc, cancel := watchEventsAsync(cs, EventFilter{})
+	defer cancel()
+
+	// A closed watch resumes from the last event seen.
+	w := <-watches
+	w.Add(testEvent("first", apiv1.EventTypeNormal, "Pulled", "Pod", "7"))
+	<-events
+	w.Stop()
+
+	// An expired one starts over with a new list.
+	w = <-watches
+	w.Error(&metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGone, Reason: metav1.StatusReasonExpired})
+
+	w = <-watches
+	w.Add(testEvent("second", apiv1.EventTypeNormal, "Started", "Pod", "9"))
+	if ev := <-events; ev.Name != "second" {
+		t.Errorf("handled %q, want the event of the new watch", ev.Name)
+	}
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatalf("WatchEvents() = %v", err)
+	}
+
+	restrictions := watchRestrictions(cs)
+	if len(restrictions) != 3 {
+		t.Fatalf("%d watches, want 3", len(restrictions))
+	}
+	if rv := restrictions[1].ResourceVersion; rv != "7" {
+		t.Errorf("second watch from resource version %q, want the last event's 7", rv)
+	}
+	if n := countActions(cs, "list", "events"); n != 2 {
+		t.Errorf("%d lists, want one initially and one after the expired watch", n)
+	}
+}
+
+func TestWatchEventsWatchError(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	watches := fakeEventWatches(cs)
+	_, errc, cancel := watchEventsAsync(cs, EventFilter{})
+	defer cancel()
+
+	(<-watches).Error(&metav1.Status{Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden})
+
+	if err := <-errc; !apierrors.IsForbidden(errors.Cause(err)) {
+		t.Fatalf("WatchEvents() = %v, want the Forbidden status", err)
+	}
+	if n := len(watchRestrictions(cs)); n != 1 {
+		t.Errorf("%d watches, want the failed watch not re-established", n)
+	}
+}
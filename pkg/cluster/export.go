@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+const exportPageSize = 500
+
+// Metadata fields set by the API server that must not be carried over when
+// the object is re-created elsewhere.
+var serverManagedFields = []string{
+	"creationTimestamp",
+	"generation",
+	"managedFields",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+// ExportResources writes every object of the given resource in namespace, or
+// in all namespaces when namespace is empty, to w as a stream of YAML
+// documents. Status and server-managed metadata are removed.
+func ExportResources(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, namespace string, w io.Writer) error {
+	resource := dynamicClient.Resource(gvr).Namespace(namespace)
+	opts := metav1.ListOptions{Limit: exportPageSize}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		list, err := resource.List(opts)
+		if err != nil {
+			return errors.Wrapf(err, "listing %s", gvr.Resource)
+		}
+
+		for i := range list.Items {
+			if err := writeExported(w, &list.Items[i]); err != nil {
+				return err
+			}
+		}
+
+		if list.GetContinue() == "" {
+			return nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+func writeExported(w io.Writer, obj *unstructured.Unstructured) error {
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range serverManagedFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s %q", obj.GetKind(), obj.GetName())
+	}
+
+	if _, err := io.WriteString(w, "---\n"); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
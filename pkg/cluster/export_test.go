@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+var configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// testConfigMap returns a config map as read from the API server, with
+// status and server-managed metadata set.
+func testConfigMap(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              name,
+			"namespace":         namespace,
+			"labels":            map[string]interface{}{"app": "web"},
+			"uid":               "0f3b2c1d-" + name,
+			"resourceVersion":   "42",
+			"generation":        int64(3),
+			"creationTimestamp": "2020-01-02T03:04:05Z",
+			"selfLink":          "/api/v1/namespaces/" + namespace + "/configmaps/" + name,
+			"managedFields": []interface{}{map[string]interface{}{
+				"manager":   "kubectl",
+				"operation": "Apply",
+			}},
+		},
+		"data":   map[string]interface{}{"key": "value"},
+		"status": map[string]interface{}{"observed": true},
+	}}
+}
+
+// exportedObjects splits the YAML stream written by ExportResources.
+func exportedObjects(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var objs []map[string]interface{}
+	for _, doc := range strings.Split(out, "---\n") {
+		if doc == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatalf("exported document %q: %v", doc, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+func TestExportResources(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		testConfigMap("apps", "settings"),
+		testConfigMap("other", "unrelated"),
+	)
+
+	var out bytes.Buffer
+	if err := ExportResources(context.Background(), client, configMapsResource, "apps", &out); err != nil {
+		t.Fatalf("ExportResources() = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "---\n") {
+		t.Errorf("output %q does not start with a document separator", out.String())
+	}
+
+	objs := exportedObjects(t, out.String())
+	if len(objs) != 1 {
+		t.Fatalf("exported %d objects, want the one in namespace apps", len(objs))
+	}
+	obj := objs[0]
+	if _, ok := obj["status"]; ok {
+		t.Error("status was exported")
+	}
+	metadata := obj["metadata"].(map[string]interface{})
+	for _, field := range []string{"managedFields", "uid", "resourceVersion", "generation", "creationTimestamp", "selfLink"} {
+		if _, ok := metadata[field]; ok {
+			t.Errorf("metadata.%s was exported", field)
+		}
+	}
+	if metadata["name"] != "settings" || metadata["namespace"] != "apps" || metadata["labels"] == nil {
+		t.Errorf("metadata = %v, want the name, namespace and labels kept", metadata)
+	}
+	if obj["kind"] != "ConfigMap" || obj["data"].(map[string]interface{})["key"] != "value" {
+		t.Errorf("exported %v, want the kind and data kept", obj)
+	}
+}
+
+func TestExportResourcesAllNamespaces(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		testConfigMap("apps", "settings"),
+		testConfigMap("other", "unrelated"),
+	)
+
+	var out bytes.Buffer
+	if err := ExportResources(context.Background(), client, configMapsResource, "", &out); err != nil {
+		t.Fatalf("ExportResources() = %v", err)
+	}
+	if objs := exportedObjects(t, out.String()); len(objs) != 2 {
+		t.Errorf("exported %d objects, want both namespaces", len(objs))
+	}
+}
+
+func TestExportResourcesListError(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("list", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+
+	var out bytes.Buffer
+	err := ExportResources(context.Background(), client, configMapsResource, "apps", &out)
+	if err == nil || !strings.Contains(err.Error(), "listing configmaps") {
+		t.Errorf("ExportResources() = %v, want a list error", err)
+	}
+}
+
+func TestExportResourcesCancelled(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), testConfigMap("apps", "settings"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	if err := ExportResources(ctx, client, configMapsResource, "apps", &out); err != context.Canceled {
+		t.Errorf("ExportResources() = %v, want %v", err, context.Canceled)
+	}
+	if len(client.Actions()) != 0 || out.Len() != 0 {
+		t.Errorf("listed %d times and wrote %q after cancellation", len(client.Actions()), out.String())
+	}
+}
@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WaitForJob blocks until the Job completes, returning an error describing the
+// failure if the Job fails or ctx is done first.
+func WaitForJob(ctx context.Context, cs kubernetes.Interface, namespace, name string) error {
+	jobs := cs.BatchV1().Jobs(namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	var backoff rewatchBackoff
+	for {
+		opts, err := withContext(ctx, metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			return err
+		}
+		list, err := jobs.List(opts)
+		if err != nil {
+			return errors.Wrapf(err, "getting job %s/%s", namespace, name)
+		}
+		if len(list.Items) == 0 {
+			return errors.Errorf("job %s/%s not found", namespace, name)
+		}
+		if done, err := jobFinished(&list.Items[0]); done {
+			return err
+		}
+
+		opts, err = withContext(ctx, metav1.ListOptions{
+			FieldSelector:   selector,
+			ResourceVersion: list.ResourceVersion,
+		})
+		if err != nil {
+			return err
+		}
+		w, err := jobs.Watch(opts)
+		if err != nil {
+			return errors.Wrapf(err, "watching job %s/%s", namespace, name)
+		}
+		backoff.started()
+		done, err := watchJob(ctx, w)
+		w.Stop()
+		if done {
+			return err
+		}
+		if err := backoff.wait(ctx); err != nil {
+			return err
+		}
+		log.WithField("job", namespace+"/"+name).Debug("Re-establishing job watch")
+	}
+}
+
+// watchJob returns true once the job has finished, ctx is done or the watch
+// failed, and false if the watch closed or expired and must be re-established.
+func watchJob(ctx context.Context, w watch.Interface) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			switch e.Type {
+			case watch.Deleted:
+				return true, errors.New("job was deleted")
+			case watch.Error:
+				status := apierrors.FromObject(e.Object)
+				if apierrors.IsResourceExpired(status) || apierrors.IsGone(status) {
+					return false, nil
+				}
+				return true, errors.Wrap(status, "job watch failed")
+			}
+			job, ok := e.Object.(*batchv1.Job)
+			if !ok {
+				continue
+			}
+			if done, err := jobFinished(job); done {
+				return true, err
+			}
+		}
+	}
+}
+
+func jobFinished(job *batchv1.Job) (bool, error) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			log.WithField("job", job.Namespace+"/"+job.Name).Info("Job completed")
+			return true, nil
+		case batchv1.JobFailed:
+			if c.Reason == "BackoffLimitExceeded" {
+				return true, errors.Errorf("job %s/%s exceeded its backoff limit after %d failed pods: %s",
+					job.Namespace, job.Name, job.Status.Failed, c.Message)
+			}
+			return true, errors.Errorf("job %s/%s failed with %d failed pods: %s: %s",
+				job.Namespace, job.Name, job.Status.Failed, c.Reason, c.Message)
+		}
+	}
+	return false, nil
+}
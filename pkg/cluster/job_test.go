@@ -0,0 +1,214 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testJob(conditions ...batchv1.JobCondition) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "migrate"},
+		Status:     batchv1.JobStatus{Conditions: conditions},
+	}
+}
+
+func jobCondition(conditionType batchv1.JobConditionType, reason string) batchv1.JobCondition {
+	return batchv1.JobCondition{Type: conditionType, Status: apiv1.ConditionTrue, Reason: reason, Message: "details"}
+}
+
+// fakeJobWatches makes the clientset hand out fake job watchers that the test
+// feeds with events.
+func fakeJobWatches(cs *fake.Clientset) chan *watch.FakeWatcher {
+	watches := make(chan *watch.FakeWatcher, 10)
+	cs.PrependWatchReactor("jobs", func(k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(10, false)
+		watches <- w
+		return true, w, nil
+	})
+	return watches
+}
+
+func waitForJobAsync(ctx context.Context, cs *fake.Clientset) chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- WaitForJob(ctx, cs, "default", "migrate")
+	}()
+	return errc
+}
+
+func TestWaitForJobFinished(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     *batchv1.Job
+		wantErr string
+	}{
+		{"complete", testJob(jobCondition(batchv1.JobComplete, "")), ""},
+		{"failed", testJob(jobCondition(batchv1.JobFailed, "DeadlineExceeded")), "failed with 0 failed pods: DeadlineExceeded"},
+		{"backoff limit", testJob(jobCondition(batchv1.JobFailed, "BackoffLimitExceeded")), "exceeded its backoff limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(tt.job)
+			err := WaitForJob(context.Background(), cs, "default", "migrate")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("WaitForJob() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("WaitForJob() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if n := countActions(cs, "watch", "jobs"); n != 0 {
+				t.Errorf("%d watches, want none for a finished job", n)
+			}
+		})
+	}
+}
+
+func TestWaitForJobWatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   func(w *watch.FakeWatcher)
+		wantErr string
+	}{
+		{"complete", func(w *watch.FakeWatcher) { w.Modify(testJob(jobCondition(batchv1.JobComplete, ""))) }, ""},
+		{"failed", func(w *watch.FakeWatcher) { w.Modify(testJob(jobCondition(batchv1.JobFailed, "BackoffLimitExceeded"))) }, "exceeded its backoff limit"},
+		{"deleted", func(w *watch.FakeWatcher) { w.Delete(testJob()) }, "job was deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(testJob())
+			watches := fakeJobWatches(cs)
+			errc := waitForJobAsync(context.Background(), cs)
+
+			w := <-watches
+			w.Modify(testJob())
+			tt.event(w)
+
+			err := <-errc
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("WaitForJob() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("WaitForJob() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if n := countActions(cs, "watch", "jobs"); n != 1 {
+				t.Errorf("%d watches, want 1", n)
+			}
+		})
+	}
+}
+
+func TestWaitForJobWatchErrorIsReturned(t *testing.T) {
+	cs := fake.NewSimpleClientset(testJob())
+	watches := fakeJobWatches(cs)
+	errc := waitForJobAsync(context.Background(), cs)
+
+	(<-watches).Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusForbidden,
+		Reason: metav1.StatusReasonForbidden,
+	})
+
+	if err := <-errc; !apierrors.IsForbidden(errors.Cause(err)) {
+		t.Fatalf("WaitForJob() = %v, want the Forbidden status", err)
+	}
+}
+
+func TestWaitForJobRewatches(t *testing.T) {
+	cs := fake.NewSimpleClientset(testJob())
+	watches := fakeJobWatches(cs)
+	errc := waitForJobAsync(context.Background(), cs)
+
+	// An expired watch and a closed one are both re-established after
+	// listing the job again.
+	(<-watches).Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusGone,
+		Reason: metav1.StatusReasonExpired,
+	})
+	(<-watches).Stop()
+	(<-watches).Modify(testJob(jobCondition(batchv1.JobComplete, "")))
+
+	if err := <-errc; err != nil {
+		t.Fatalf("WaitForJob() = %v", err)
+	}
+	if n := countActions(cs, "watch", "jobs"); n != 3 {
+		t.Errorf("%d watches, want 3", n)
+	}
+	if n := countActions(cs, "list", "jobs"); n != 3 {
+		t.Errorf("%d lists, want one per watch", n)
+	}
+}
+
+func TestWaitForJobCancelledBeforeList(t *testing.T) {
+	cs := fake.NewSimpleClientset(testJob())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WaitForJob(ctx, cs, "default", "migrate"); err != context.Canceled {
+		t.Errorf("WaitForJob() = %v, want context.Canceled", err)
+	}
+	if n := len(cs.Actions()); n != 0 {
+		t.Errorf("%d API calls, want none once ctx is done", n)
+	}
+}
+
+func TestRewatchBackoff(t *testing.T) {
+	var b rewatchBackoff
+	for _, want := range []time.Duration{minRewatchDelay, 2 * minRewatchDelay} {
+		b.started()
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if b.delay != want {
+			t.Errorf("delay = %s after a short watch, want %s", b.delay, want)
+		}
+	}
+
+	b.start = time.Now().Add(-healthyWatchDuration)
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b.delay != 0 {
+		t.Errorf("delay = %s after a long watch, want none", b.delay)
+	}
+
+	b.delay = maxRewatchDelay
+	b.started()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.wait(ctx); err != context.Canceled {
+		t.Errorf("wait() = %v, want context.Canceled", err)
+	}
+	if b.delay != maxRewatchDelay {
+		t.Errorf("delay = %s, want it capped at %s", b.delay, maxRewatchDelay)
+	}
+}
+
+func TestWaitForJobNotFound(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	if err := WaitForJob(context.Background(), cs, "default", "migrate"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("WaitForJob() = %v, want a not found error", err)
+	}
+}
+
+func TestWaitForJobTimeout(t *testing.T) {
+	cs := fake.NewSimpleClientset(testJob())
+	fakeJobWatches(cs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := WaitForJob(ctx, cs, "default", "migrate"); err != context.DeadlineExceeded {
+		t.Errorf("WaitForJob() = %v, want context.DeadlineExceeded", err)
+	}
+}
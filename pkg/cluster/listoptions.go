@@ -0,0 +1,28 @@
+package cluster
+
+import (
+	"context"
+	"math"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// withContext returns opts with TimeoutSeconds set from the deadline of ctx,
+// so that the API server ends lists and watches the typed clients cannot
+// cancel, as they take no context. It fails if ctx is already done.
+func withContext(ctx context.Context, opts metav1.ListOptions) (metav1.ListOptions, error) {
+	if err := ctx.Err(); err != nil {
+		return opts, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return opts, nil
+	}
+	seconds := int64(math.Ceil(time.Until(deadline).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	opts.TimeoutSeconds = &seconds
+	return opts, nil
+}
@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithContext(t *testing.T) {
+	opts, err := withContext(context.Background(), metav1.ListOptions{ResourceVersion: "5"})
+	if err != nil || opts.TimeoutSeconds != nil || opts.ResourceVersion != "5" {
+		t.Errorf("withContext() without deadline = %+v, %v, want the options unchanged", opts, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	opts, err = withContext(ctx, metav1.ListOptions{})
+	if err != nil || opts.TimeoutSeconds == nil || *opts.TimeoutSeconds != 90 {
+		t.Errorf("withContext() with 90s left = %+v, %v, want TimeoutSeconds 90", opts, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	opts, err = withContext(ctx, metav1.ListOptions{})
+	if err != nil || opts.TimeoutSeconds == nil || *opts.TimeoutSeconds != 1 {
+		t.Errorf("withContext() with 300ms left = %+v, %v, want TimeoutSeconds 1", opts, err)
+	}
+
+	cancel()
+	if _, err := withContext(ctx, metav1.ListOptions{}); err != context.Canceled {
+		t.Errorf("withContext() after cancel = %v, want context.Canceled", err)
+	}
+}
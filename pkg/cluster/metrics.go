@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// MetricsUnavailableError is returned by TopPods when the metrics API is not
+// served by the cluster, usually because metrics-server is not installed.
+type MetricsUnavailableError struct {
+	Err error
+}
+
+func (e *MetricsUnavailableError) Error() string {
+	return "metrics API not available, is metrics-server installed? " + e.Err.Error()
+}
+
+func (e *MetricsUnavailableError) Cause() error {
+	return e.Err
+}
+
+type ContainerMetrics struct {
+	Name        string
+	CPUMilli    int64
+	MemoryBytes int64
+}
+
+type PodMetrics struct {
+	Namespace   string
+	Name        string
+	CPUMilli    int64
+	MemoryBytes int64
+	Containers  []ContainerMetrics
+}
+
+// TopPods returns the current CPU and memory usage of the pods in namespace,
+// or in all namespaces when namespace is empty.
+func TopPods(ctx context.Context, metricsClient metrics.Interface, namespace string) ([]PodMetrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	list, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+			return nil, &MetricsUnavailableError{Err: err}
+		}
+		return nil, errors.Wrap(err, "listing pod metrics")
+	}
+
+	result := make([]PodMetrics, 0, len(list.Items))
+	for _, item := range list.Items {
+		pm := PodMetrics{
+			Namespace:  item.Namespace,
+			Name:       item.Name,
+			Containers: make([]ContainerMetrics, 0, len(item.Containers)),
+		}
+		for _, c := range item.Containers {
+			cm := ContainerMetrics{
+				Name:        c.Name,
+				CPUMilli:    c.Usage.Cpu().MilliValue(),
+				MemoryBytes: c.Usage.Memory().Value(),
+			}
+			pm.CPUMilli += cm.CPUMilli
+			pm.MemoryBytes += cm.MemoryBytes
+			pm.Containers = append(pm.Containers, cm)
+		}
+		result = append(result, pm)
+	}
+	return result, nil
+}
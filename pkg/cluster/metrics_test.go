@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestTopPods(t *testing.T) {
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Containers: []metricsv1beta1.ContainerMetrics{
+				{Name: "app", Usage: usage("250m", "128Mi")},
+				{Name: "sidecar", Usage: usage("50m", "32Mi")},
+			},
+		}}}, nil
+	})
+
+	pods, err := TopPods(context.Background(), mc, "default")
+	if err != nil {
+		t.Fatalf("TopPods() = %v", err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("TopPods() returned %d pods, want 1", len(pods))
+	}
+	pod := pods[0]
+	if pod.Name != "web" || pod.CPUMilli != 300 || pod.MemoryBytes != 160<<20 {
+		t.Errorf("TopPods() = %+v, want web using 300m CPU and 160Mi", pod)
+	}
+	if len(pod.Containers) != 2 || pod.Containers[0].CPUMilli != 250 {
+		t.Errorf("Containers = %+v", pod.Containers)
+	}
+}
+
+func TestTopPodsMetricsServerMissing(t *testing.T) {
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}, "")
+	})
+
+	_, err := TopPods(context.Background(), mc, "default")
+	if _, ok := err.(*MetricsUnavailableError); !ok {
+		t.Fatalf("TopPods() = %v, want a *MetricsUnavailableError", err)
+	}
+	if !apierrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("cause of %v is not the NotFound error", err)
+	}
+}
+
+func usage(cpu, memory string) apiv1.ResourceList {
+	return apiv1.ResourceList{
+		apiv1.ResourceCPU:    resource.MustParse(cpu),
+		apiv1.ResourceMemory: resource.MustParse(memory),
+	}
+}
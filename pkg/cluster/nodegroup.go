@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Label set by EKS on nodes of a managed node group.
+const nodegroupLabel = "eks.amazonaws.com/nodegroup"
+
+// DrainNodegroup cordons every node of the managed node group and then drains
+// them, at most opts.Concurrency at a time. The session needs the
+// eks:DescribeNodegroup permission.
+func DrainNodegroup(ctx context.Context, cs kubernetes.Interface, sess *session.Session, clusterName, nodegroupName string, opts DrainOptions) error {
+	_, err := eks.New(sess).DescribeNodegroupWithContext(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "describing node group %q", nodegroupName)
+	}
+
+	nodes, err := cs.CoreV1().Nodes().List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{nodegroupLabel: nodegroupName}).String(),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "listing nodes of node group %q", nodegroupName)
+	}
+
+	logger := log.WithFields(log.Fields{
+		"cluster":   clusterName,
+		"nodegroup": nodegroupName,
+	})
+	logger.Infof("Draining %d nodes", len(nodes.Items))
+
+	// Cordon everything first so evicted pods don't land on nodes that are
+	// about to be drained.
+	for _, node := range nodes.Items {
+		if err := CordonNode(ctx, cs, node.Name); err != nil {
+			return err
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	for _, node := range nodes.Items {
+		name := node.Name
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := DrainNode(ctx, cs, name, opts); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return errors.Wrapf(firstErr, "draining node group %q", nodegroupName)
+	}
+	if err := ctx.Err(); err != nil {
+		return errors.Wrapf(err, "draining node group %q", nodegroupName)
+	}
+	logger.Info("Drained node group")
+	return nil
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type NodeHealthInfo struct {
+	Name          string
+	Ready         bool
+	Unschedulable bool
+	Conditions    map[apiv1.NodeConditionType]apiv1.ConditionStatus
+	Taints        []apiv1.Taint
+}
+
+// NodeHealth returns the conditions, taints and schedulability of every node.
+func NodeHealth(ctx context.Context, cs kubernetes.Interface) ([]NodeHealthInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := cs.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing nodes")
+	}
+
+	health := make([]NodeHealthInfo, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		info := NodeHealthInfo{
+			Name:          node.Name,
+			Unschedulable: node.Spec.Unschedulable,
+			Conditions:    make(map[apiv1.NodeConditionType]apiv1.ConditionStatus, len(node.Status.Conditions)),
+			Taints:        node.Spec.Taints,
+		}
+		for _, c := range node.Status.Conditions {
+			info.Conditions[c.Type] = c.Status
+		}
+		info.Ready = info.Conditions[apiv1.NodeReady] == apiv1.ConditionTrue
+		health = append(health, info)
+	}
+	return health, nil
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNodeHealth(t *testing.T) {
+	ready := testNode("ready", "")
+	ready.Status.Conditions = []apiv1.NodeCondition{
+		{Type: apiv1.NodeReady, Status: apiv1.ConditionTrue},
+		{Type: apiv1.NodeMemoryPressure, Status: apiv1.ConditionFalse},
+	}
+	cordoned := testNode("cordoned", "")
+	cordoned.Spec.Unschedulable = true
+	cordoned.Spec.Taints = []apiv1.Taint{{Key: "node.kubernetes.io/unschedulable", Effect: apiv1.TaintEffectNoSchedule}}
+	cordoned.Status.Conditions = []apiv1.NodeCondition{{Type: apiv1.NodeReady, Status: apiv1.ConditionTrue}}
+	notReady := testNode("not-ready", "")
+	notReady.Status.Conditions = []apiv1.NodeCondition{
+		{Type: apiv1.NodeReady, Status: apiv1.ConditionUnknown},
+		{Type: apiv1.NodeDiskPressure, Status: apiv1.ConditionTrue},
+	}
+	unreported := testNode("unreported", "")
+	cs := fake.NewSimpleClientset(ready, cordoned, notReady, unreported)
+
+	health, err := NodeHealth(context.Background(), cs)
+	if err != nil {
+		t.Fatalf("NodeHealth() = %v", err)
+	}
+	got := make(map[string]NodeHealthInfo, len(health))
+	for _, info := range health {
+		got[info.Name] = info
+	}
+	want := map[string]NodeHealthInfo{
+		"ready": {
+			Name:  "ready",
+			Ready: true,
+			Conditions: map[apiv1.NodeConditionType]apiv1.ConditionStatus{
+				apiv1.NodeReady:          apiv1.ConditionTrue,
+				apiv1.NodeMemoryPressure: apiv1.ConditionFalse,
+			},
+		},
+		"cordoned": {
+			Name:          "cordoned",
+			Ready:         true,
+			Unschedulable: true,
+			Conditions:    map[apiv1.NodeConditionType]apiv1.ConditionStatus{apiv1.NodeReady: apiv1.ConditionTrue},
+			Taints:        cordoned.Spec.Taints,
+		},
+		"not-ready": {
+			Name: "not-ready",
+			Conditions: map[apiv1.NodeConditionType]apiv1.ConditionStatus{
+				apiv1.NodeReady:        apiv1.ConditionUnknown,
+				apiv1.NodeDiskPressure: apiv1.ConditionTrue,
+			},
+		},
+		"unreported": {
+			Name:       "unreported",
+			Conditions: map[apiv1.NodeConditionType]apiv1.ConditionStatus{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeHealth() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNodeHealthListError(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	if _, err := NodeHealth(context.Background(), cs); err == nil || !strings.Contains(err.Error(), "listing nodes") {
+		t.Errorf("NodeHealth() = %v, want a list error", err)
+	}
+}
+
+func TestNodeHealthCancelled(t *testing.T) {
+	cs := fake.NewSimpleClientset(testNode("ready", ""))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NodeHealth(ctx, cs); err != context.Canceled {
+		t.Errorf("NodeHealth() = %v, want %v", err, context.Canceled)
+	}
+	if len(cs.Actions()) != 0 {
+		t.Errorf("%d API calls after cancellation, want 0", len(cs.Actions()))
+	}
+}
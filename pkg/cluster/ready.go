@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WaitForNamespaceReady blocks until every pod in namespace is Ready or has
+// Succeeded. Failed pods owned by a Job are ignored since the Job controller
+// replaces them. On timeout the error lists the pods that are still not ready.
+func WaitForNamespaceReady(ctx context.Context, cs kubernetes.Interface, namespace string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pods := cs.CoreV1().Pods(namespace)
+	var state map[string]*apiv1.Pod
+	resourceVersion := ""
+
+	for {
+		if resourceVersion == "" {
+			opts, err := withContext(ctx, metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "listing pods in namespace %q", namespace)
+			}
+			list, err := pods.List(opts)
+			if err != nil {
+				return errors.Wrapf(err, "listing pods in namespace %q", namespace)
+			}
+			state = make(map[string]*apiv1.Pod, len(list.Items))
+			for i := range list.Items {
+				state[list.Items[i].Name] = &list.Items[i]
+			}
+			resourceVersion = list.ResourceVersion
+		}
+
+		pending := notReadyPods(state)
+		if len(pending) == 0 {
+			log.WithField("namespace", namespace).Info("All pods are ready")
+			return nil
+		}
+		log.WithField("namespace", namespace).Debugf("Waiting for %d pods", len(pending))
+
+		opts, err := withContext(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+		if err != nil {
+			return errors.Wrapf(err, "waiting for pods in namespace %q: not ready: %s",
+				namespace, strings.Join(pending, ", "))
+		}
+		w, err := pods.Watch(opts)
+		if err != nil {
+			return errors.Wrapf(err, "watching pods in namespace %q", namespace)
+		}
+		resourceVersion, err = applyPodEvents(ctx, w, state, resourceVersion)
+		w.Stop()
+		if err != nil {
+			return errors.Wrapf(err, "waiting for pods in namespace %q: not ready: %s",
+				namespace, strings.Join(notReadyPods(state), ", "))
+		}
+	}
+}
+
+// applyPodEvents updates state from w until all pods are ready, the watch
+// closes or ctx is done. It returns the resource version to resume from, or
+// an empty string if the pods must be listed again.
+func applyPodEvents(ctx context.Context, w watch.Interface, state map[string]*apiv1.Pod, resourceVersion string) (string, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion, ctx.Err()
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion, nil
+			}
+			if e.Type == watch.Error {
+				status := apierrors.FromObject(e.Object)
+				if apierrors.IsResourceExpired(status) || apierrors.IsGone(status) {
+					return "", nil
+				}
+				return resourceVersion, errors.Wrap(status, "pod watch failed")
+			}
+			pod, ok := e.Object.(*apiv1.Pod)
+			if !ok {
+				continue
+			}
+			resourceVersion = pod.ResourceVersion
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				state[pod.Name] = pod
+			case watch.Deleted:
+				delete(state, pod.Name)
+			}
+			if len(notReadyPods(state)) == 0 {
+				return resourceVersion, nil
+			}
+		}
+	}
+}
+
+func notReadyPods(state map[string]*apiv1.Pod) []string {
+	var names []string
+	for name, pod := range state {
+		if !podDone(pod) {
+			names = append(names, name+" ("+string(pod.Status.Phase)+")")
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func podDone(pod *apiv1.Pod) bool {
+	switch pod.Status.Phase {
+	case apiv1.PodSucceeded:
+		return true
+	case apiv1.PodFailed:
+		return ownedByJob(pod)
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == apiv1.PodReady {
+			return c.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func ownedByJob(pod *apiv1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "Job" {
+			return true
+		}
+	}
+	return false
+}
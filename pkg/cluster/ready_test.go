@@ -0,0 +1,148 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testPod(name string, ready bool) *apiv1.Pod {
+	status := apiv1.ConditionFalse
+	if ready {
+		status = apiv1.ConditionTrue
+	}
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Status: apiv1.PodStatus{
+			Phase:      apiv1.PodRunning,
+			Conditions: []apiv1.PodCondition{{Type: apiv1.PodReady, Status: status}},
+		},
+	}
+}
+
+// fakePodWatches makes the clientset hand out fake watchers that the test
+// feeds with events.
+func fakePodWatches(cs *fake.Clientset) chan *watch.FakeWatcher {
+	watches := make(chan *watch.FakeWatcher, 10)
+	cs.PrependWatchReactor("pods", func(k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFakeWithChanSize(10, false)
+		watches <- w
+		return true, w, nil
+	})
+	return watches
+}
+
+func countActions(cs *fake.Clientset, verb, resource string) int {
+	n := 0
+	for _, a := range cs.Actions() {
+		if a.GetVerb() == verb && a.GetResource().Resource == resource {
+			n++
+		}
+	}
+	return n
+}
+
+func waitAsync(cs *fake.Clientset) chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- WaitForNamespaceReady(context.Background(), cs, "default", 10*time.Second)
+	}()
+	return errc
+}
+
+func TestWaitForNamespaceReadyReadyPods(t *testing.T) {
+	cs := fake.NewSimpleClientset(testPod("a", true), testPod("b", true))
+
+	if err := WaitForNamespaceReady(context.Background(), cs, "default", time.Second); err != nil {
+		t.Fatalf("WaitForNamespaceReady() = %v", err)
+	}
+	if n := countActions(cs, "watch", "pods"); n != 0 {
+		t.Errorf("%d watches, want none for ready pods", n)
+	}
+}
+
+func TestWaitForNamespaceReadyUsesOneWatch(t *testing.T) {
+	cs := fake.NewSimpleClientset(testPod("a", false), testPod("b", false), testPod("c", false))
+	watches := fakePodWatches(cs)
+	errc := waitAsync(cs)
+
+	// Watch events carry resource versions, unlike the seeded objects.
+	a, b, c := testPod("a", true), testPod("b", true), testPod("c", false)
+	a.ResourceVersion, b.ResourceVersion, c.ResourceVersion = "2", "3", "4"
+	w := <-watches
+	w.Modify(a)
+	w.Modify(b)
+	w.Delete(c)
+
+	if err := <-errc; err != nil {
+		t.Fatalf("WaitForNamespaceReady() = %v", err)
+	}
+	if n := countActions(cs, "watch", "pods"); n != 1 {
+		t.Errorf("%d watches for 3 pod events, want 1", n)
+	}
+}
+
+func TestWaitForNamespaceReadyRelistsOnExpiredWatch(t *testing.T) {
+	cs := fake.NewSimpleClientset(testPod("a", false))
+	watches := fakePodWatches(cs)
+	errc := waitAsync(cs)
+
+	// The pod became ready while the watch was expiring, so only the relist
+	// sees it.
+	w := <-watches
+	gvr := apiv1.SchemeGroupVersion.WithResource("pods")
+	if err := cs.Tracker().Update(gvr, testPod("a", true), "default"); err != nil {
+		t.Fatal(err)
+	}
+	w.Error(&metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusGone,
+		Reason: metav1.StatusReasonExpired,
+	})
+
+	if err := <-errc; err != nil {
+		t.Fatalf("WaitForNamespaceReady() = %v", err)
+	}
+	if n := countActions(cs, "list", "pods"); n != 2 {
+		t.Errorf("%d lists, want 2", n)
+	}
+}
+
+func TestWaitForNamespaceReadyWatchError(t *testing.T) {
+	cs := fake.NewSimpleClientset(testPod("a", false))
+	watches := fakePodWatches(cs)
+	errc := waitAsync(cs)
+
+	w := <-watches
+	w.Error(&metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: "forbidden",
+	})
+
+	if err := <-errc; err == nil {
+		t.Fatal("WaitForNamespaceReady() = nil, want the watch error")
+	}
+}
+
+func TestWaitForNamespaceReadyTimeout(t *testing.T) {
+	cs := fake.NewSimpleClientset(testPod("a", false))
+	fakePodWatches(cs)
+
+	err := WaitForNamespaceReady(context.Background(), cs, "default", 50*time.Millisecond)
+	if err == nil {
+		t.Fatal("WaitForNamespaceReady() = nil, want a timeout")
+	}
+	if got, want := err.Error(), "not ready: a (Running)"; !strings.Contains(got, want) {
+		t.Errorf("error %q does not list the pending pod %q", got, want)
+	}
+}
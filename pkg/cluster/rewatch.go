@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	minRewatchDelay = 100 * time.Millisecond
+	maxRewatchDelay = 10 * time.Second
+
+	// Watches lasting longer than this re-establish right away.
+	healthyWatchDuration = time.Second
+)
+
+// rewatchBackoff spaces out re-establishing a watch, so that a server closing
+// watches right after they start is not hit in a tight loop.
+type rewatchBackoff struct {
+	start time.Time
+	delay time.Duration
+}
+
+// started records that a watch was established.
+func (b *rewatchBackoff) started() {
+	b.start = time.Now()
+}
+
+// wait blocks before the watch is re-established. Each watch ending within
+// healthyWatchDuration doubles the delay, up to maxRewatchDelay. It returns
+// ctx.Err() if ctx is done first.
+func (b *rewatchBackoff) wait(ctx context.Context) error {
+	if time.Since(b.start) >= healthyWatchDuration {
+		b.delay = 0
+		return ctx.Err()
+	}
+
+	b.delay *= 2
+	if b.delay < minRewatchDelay {
+		b.delay = minRewatchDelay
+	}
+	if b.delay > maxRewatchDelay {
+		b.delay = maxRewatchDelay
+	}
+
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MyRules returns the RBAC rules the authenticated identity is allowed in
+// namespace, the programmatic equivalent of kubectl auth can-i --list.
+func MyRules(ctx context.Context, cs kubernetes.Interface, namespace string) ([]rbacv1.PolicyRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	review, err := cs.AuthorizationV1().SelfSubjectRulesReviews().Create(&authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reviewing rules in namespace %q", namespace)
+	}
+
+	if review.Status.Incomplete {
+		log.WithField("namespace", namespace).Warnf("Rules review is incomplete: %s", review.Status.EvaluationError)
+	}
+
+	rules := make([]rbacv1.PolicyRule, 0, len(review.Status.ResourceRules)+len(review.Status.NonResourceRules))
+	for _, r := range review.Status.ResourceRules {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:         r.Verbs,
+			APIGroups:     r.APIGroups,
+			Resources:     r.Resources,
+			ResourceNames: r.ResourceNames,
+		})
+	}
+	for _, r := range review.Status.NonResourceRules {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:           r.Verbs,
+			NonResourceURLs: r.NonResourceURLs,
+		})
+	}
+	return rules, nil
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeRulesReview makes the clientset answer rules reviews with status,
+// recording the namespaces reviewed.
+func fakeRulesReview(cs *fake.Clientset, status authorizationv1.SubjectRulesReviewStatus) *[]string {
+	var namespaces []string
+	cs.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectRulesReview).DeepCopy()
+		namespaces = append(namespaces, review.Spec.Namespace)
+		review.Status = status
+		return true, review, nil
+	})
+	return &namespaces
+}
+
+func TestMyRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		status authorizationv1.SubjectRulesReviewStatus
+		want   []rbacv1.PolicyRule
+	}{
+		{
+			name: "resource and non-resource rules",
+			status: authorizationv1.SubjectRulesReviewStatus{
+				ResourceRules: []authorizationv1.ResourceRule{
+					{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+					{Verbs: []string{"update"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"web"}},
+				},
+				NonResourceRules: []authorizationv1.NonResourceRule{
+					{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}},
+				},
+			},
+			want: []rbacv1.PolicyRule{
+				{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+				{Verbs: []string{"update"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"web"}},
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}},
+			},
+		},
+		{
+			name: "incomplete review",
+			status: authorizationv1.SubjectRulesReviewStatus{
+				ResourceRules:   []authorizationv1.ResourceRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}}},
+				Incomplete:      true,
+				EvaluationError: "webhook authorizer does not support rules reviews",
+			},
+			want: []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}}},
+		},
+		{
+			name: "no rules",
+			want: []rbacv1.PolicyRule{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			namespaces := fakeRulesReview(cs, tt.status)
+
+			rules, err := MyRules(context.Background(), cs, "apps")
+			if err != nil {
+				t.Fatalf("MyRules() = %v", err)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("MyRules() = %+v, want %+v", rules, tt.want)
+			}
+			if !reflect.DeepEqual(*namespaces, []string{"apps"}) {
+				t.Errorf("reviewed namespaces %q, want apps", *namespaces)
+			}
+		})
+	}
+}
+
+func TestMyRulesError(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	// The fake rules review client requires an object along with the error.
+	cs.PrependReactor("create", "selfsubjectrulesreviews", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectRulesReview{}, errors.New("forbidden")
+	})
+
+	_, err := MyRules(context.Background(), cs, "apps")
+	if err == nil || !strings.Contains(err.Error(), `reviewing rules in namespace "apps"`) {
+		t.Errorf("MyRules() = %v, want a review error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// minTokenTTL is the shortest token lifetime accepted by the TokenRequest API.
+const minTokenTTL = 10 * time.Minute
+
+// CreateServiceAccountToken requests a bound token for the service account
+// that expires after ttl and is valid for the given audiences. A zero ttl
+// leaves the lifetime to the API server, one hour by default; otherwise it
+// must be at least 10 minutes. It returns the token and its expiry time.
+func CreateServiceAccountToken(ctx context.Context, cs kubernetes.Interface, namespace, saName string, ttl time.Duration, audiences []string) (string, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	if ttl != 0 && ttl < minTokenTTL {
+		return "", time.Time{}, errors.Errorf("token ttl %s is shorter than the minimum of %s", ttl, minTokenTTL)
+	}
+
+	req := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences: audiences,
+		},
+	}
+	if ttl != 0 {
+		seconds := int64(ttl / time.Second)
+		req.Spec.ExpirationSeconds = &seconds
+	}
+
+	resp, err := cs.CoreV1().ServiceAccounts(namespace).CreateToken(saName, req)
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "creating token for service account %s/%s", namespace, saName)
+	}
+	return resp.Status.Token, resp.Status.ExpirationTimestamp.Time, nil
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeTokenRequests answers TokenRequests like the API server does, with an
+// hour long token by default, and records the requests.
+func fakeTokenRequests(cs *fake.Clientset) *[]*authenticationv1.TokenRequest {
+	var requests []*authenticationv1.TokenRequest
+	cs.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		req := create.GetObject().(*authenticationv1.TokenRequest)
+		requests = append(requests, req)
+
+		ttl := time.Hour
+		if req.Spec.ExpirationSeconds != nil {
+			ttl = time.Duration(*req.Spec.ExpirationSeconds) * time.Second
+		}
+		resp := req.DeepCopy()
+		resp.Status = authenticationv1.TokenRequestStatus{
+			Token:               "sa-token",
+			ExpirationTimestamp: metav1.NewTime(time.Now().Add(ttl)),
+		}
+		return true, resp, nil
+	})
+	return &requests
+}
+
+func TestCreateServiceAccountToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		wantSeconds *int64
+		wantErr     bool
+	}{
+		{name: "server default", ttl: 0},
+		{name: "too short", ttl: 5 * time.Minute, wantErr: true},
+		{name: "one hour", ttl: time.Hour, wantSeconds: int64Ptr(3600)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			requests := fakeTokenRequests(cs)
+
+			tok, expiry, err := CreateServiceAccountToken(context.Background(), cs, "default", "deployer", tt.ttl, []string{"vault"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("CreateServiceAccountToken() = nil error, want an error")
+				}
+				if len(*requests) != 0 {
+					t.Errorf("%d token requests sent, want none", len(*requests))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateServiceAccountToken() = %v", err)
+			}
+			if tok != "sa-token" || !expiry.After(time.Now()) {
+				t.Errorf("CreateServiceAccountToken() = %q, %s", tok, expiry)
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("%d token requests sent, want 1", len(*requests))
+			}
+			spec := (*requests)[0].Spec
+			switch {
+			case tt.wantSeconds == nil && spec.ExpirationSeconds != nil:
+				t.Errorf("ExpirationSeconds = %d, want unset", *spec.ExpirationSeconds)
+			case tt.wantSeconds != nil && (spec.ExpirationSeconds == nil || *spec.ExpirationSeconds != *tt.wantSeconds):
+				t.Errorf("ExpirationSeconds = %v, want %d", spec.ExpirationSeconds, *tt.wantSeconds)
+			}
+			if len(spec.Audiences) != 1 || spec.Audiences[0] != "vault" {
+				t.Errorf("Audiences = %v, want [vault]", spec.Audiences)
+			}
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}